@@ -0,0 +1,150 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxAPITokenNameLength = 100
+
+type apiTokenResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// Token is only ever included in the response that creates it
+	Token string `json:"token,omitempty"`
+}
+
+func apiTokenToResponse(apiToken database.ApiToken) apiTokenResponse {
+	resp := apiTokenResponse{
+		ID:        apiToken.ID,
+		Name:      apiToken.Name,
+		Scopes:    apiToken.Scopes,
+		CreatedAt: apiToken.CreatedAt,
+	}
+	if apiToken.ExpiresAt.Valid {
+		resp.ExpiresAt = &apiToken.ExpiresAt.Time
+	}
+	if apiToken.LastUsedAt.Valid {
+		resp.LastUsedAt = &apiToken.LastUsedAt.Time
+	}
+	return resp
+}
+
+// createAPIToken issues a personal API token limited to the requested scopes.
+// Only its hash is stored, so the token itself is shown to the user once.
+func (cfg *apiConfig) createAPIToken(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	jwtUser, ok := cfg.authorizeSession(w, r)
+	if !ok {
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	if params.Name == "" || utf8.RuneCountInString(params.Name) > maxAPITokenNameLength {
+		respondWithError(w, 400, "name must be 1 to 100 characters")
+		return
+	}
+	if len(params.Scopes) == 0 {
+		respondWithError(w, 400, "at least one scope is required")
+		return
+	}
+	for _, scope := range params.Scopes {
+		if !auth.ValidScope(scope) {
+			respondWithError(w, 400, "unknown scope "+scope)
+			return
+		}
+	}
+	slices.Sort(params.Scopes)
+	params.Scopes = slices.Compact(params.Scopes)
+	expiresAt := sql.NullTime{}
+	if params.ExpiresAt != nil {
+		if !params.ExpiresAt.After(time.Now()) {
+			respondWithError(w, 400, "expires_at must be in the future")
+			return
+		}
+		expiresAt = sql.NullTime{Time: params.ExpiresAt.Local(), Valid: true}
+	}
+
+	token, err := auth.MakeAPIToken()
+	if err != nil {
+		respondWithError(w, 500, "cannot create token")
+		return
+	}
+	apiToken, err := cfg.db_query.CreateAPIToken(r.Context(), database.CreateAPITokenParams{
+		UserID:    jwtUser,
+		Name:      params.Name,
+		TokenHash: auth.HashToken(token),
+		Scopes:    params.Scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot create token")
+		return
+	}
+	resp := apiTokenToResponse(apiToken)
+	resp.Token = token
+	respondWithJSON(w, 201, resp)
+}
+
+func (cfg *apiConfig) getAPITokens(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorizeSession(w, r)
+	if !ok {
+		return
+	}
+	apiTokens, err := cfg.db_query.ListAPITokens(r.Context(), jwtUser)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve tokens")
+		return
+	}
+	responseTokens := []apiTokenResponse{}
+	for _, apiToken := range apiTokens {
+		responseTokens = append(responseTokens, apiTokenToResponse(apiToken))
+	}
+	respondWithJSON(w, 200, responseTokens)
+}
+
+func (cfg *apiConfig) revokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorizeSession(w, r)
+	if !ok {
+		return
+	}
+	tokenID, err := uuid.Parse(r.PathValue("tokenID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid token ID format")
+		return
+	}
+	revoked, err := cfg.db_query.RevokeAPIToken(r.Context(), database.RevokeAPITokenParams{ID: tokenID, UserID: jwtUser})
+	if err != nil {
+		respondWithError(w, 500, "cannot revoke token")
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, 404, "token not found")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
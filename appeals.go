@@ -0,0 +1,222 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxAppealLength = 2000
+
+// Decisions a moderator can make on an appeal.
+const (
+	appealUphold = "uphold"
+	appealLift   = "lift"
+)
+
+type appealResponse struct {
+	ID               uuid.UUID  `json:"id"`
+	SuspensionID     uuid.UUID  `json:"suspension_id"`
+	UserID           uuid.UUID  `json:"user_id"`
+	Message          string     `json:"message"`
+	CreatedAt        time.Time  `json:"created_at"`
+	SuspensionReason string     `json:"suspension_reason,omitempty"`
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty"`
+	SuspensionEndsAt *time.Time `json:"suspension_ends_at,omitempty"`
+}
+
+// submitAppeal lets a suspended user appeal their suspension, once. They
+// cannot log in, so they sign the appeal with their email and password.
+func (cfg *apiConfig) submitAppeal(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+		Message  string `json:"message"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		w.WriteHeader(400)
+		return
+	}
+	if params.Message == "" || utf8.RuneCountInString(params.Message) > maxAppealLength {
+		respondWithError(w, 400, "a message of at most 2000 characters is required")
+		return
+	}
+
+	dbUser, retryAfter, err := cfg.checkLogin(r, params.Email, params.Password)
+	if err != nil {
+		respondWithLoginError(w, retryAfter, err)
+		return
+	}
+	suspension, suspended, err := cfg.activeSuspension(r.Context(), dbUser.ID, suspensionKind)
+	if err != nil {
+		respondWithError(w, 500, "cannot submit appeal")
+		return
+	}
+	if !suspended {
+		respondWithError(w, 400, "account is not suspended")
+		return
+	}
+
+	appeal, err := cfg.db_query.CreateAppeal(r.Context(), database.CreateAppealParams{
+		SuspensionID: suspension.ID,
+		UserID:       dbUser.ID,
+		Message:      params.Message,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "suspension already appealed")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "cannot submit appeal")
+		return
+	}
+	respondWithJSON(w, 201, appealResponse{
+		ID:           appeal.ID,
+		SuspensionID: appeal.SuspensionID,
+		UserID:       appeal.UserID,
+		Message:      appeal.Message,
+		CreatedAt:    appeal.CreatedAt,
+	})
+}
+
+// getAppeals lists open appeals, oldest first, with the suspensions they
+// appeal.
+func (cfg *apiConfig) getAppeals(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	afterCreatedAt, afterID := page.afterCursor()
+	appeals, err := cfg.db_query.ListOpenAppeals(r.Context(), database.ListOpenAppealsParams{
+		AfterCreatedAt: afterCreatedAt,
+		AfterID:        afterID,
+		Limit:          page.Limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve appeals")
+		return
+	}
+	if len(appeals) > int(page.Limit) {
+		appeals = appeals[:page.Limit]
+		last := appeals[len(appeals)-1]
+		setNextLink(w, r, chirpCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+
+	responseAppeals := []appealResponse{}
+	for _, appeal := range appeals {
+		resp := appealResponse{
+			ID:               appeal.ID,
+			SuspensionID:     appeal.SuspensionID,
+			UserID:           appeal.UserID,
+			Message:          appeal.Message,
+			CreatedAt:        appeal.CreatedAt,
+			SuspensionReason: appeal.SuspensionReason,
+			SuspendedAt:      &appeal.SuspendedAt,
+		}
+		if appeal.SuspensionEndsAt.Valid {
+			resp.SuspensionEndsAt = &appeal.SuspensionEndsAt.Time
+		}
+		responseAppeals = append(responseAppeals, resp)
+	}
+	respondWithJSON(w, 200, responseAppeals)
+}
+
+// resolveAppeal upholds an appeal's suspension or lifts it, recording the
+// decision as a moderation action.
+func (cfg *apiConfig) resolveAppeal(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Decision string `json:"decision"`
+		Note     string `json:"note"`
+	}
+
+	moderator, _ := principalFrom(r.Context())
+	appealID, err := uuid.Parse(r.PathValue("appealID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid appeal ID format")
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		w.WriteHeader(400)
+		return
+	}
+	if params.Decision != appealUphold && params.Decision != appealLift {
+		respondWithError(w, 400, "decision must be uphold or lift")
+		return
+	}
+	if utf8.RuneCountInString(params.Note) > maxModerationNoteLength {
+		respondWithError(w, 400, "note must be at most 1000 characters")
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "cannot resolve appeal")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	appeal, err := qtx.GetAppealForUpdate(r.Context(), appealID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "appeal not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "cannot resolve appeal")
+		return
+	}
+	if appeal.ResolvedAt.Valid {
+		respondWithError(w, 409, "appeal already resolved")
+		return
+	}
+
+	action := "uphold_appeal"
+	if params.Decision == appealLift {
+		action = "lift_" + suspensionKind
+		err = qtx.LiftSuspension(r.Context(), appeal.SuspensionID)
+		if err != nil {
+			respondWithError(w, 500, "cannot resolve appeal")
+			return
+		}
+	}
+	recorded, err := qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+		ModeratorID: uuid.NullUUID{UUID: moderator.UserID, Valid: true},
+		Action:      action,
+		UserID:      uuid.NullUUID{UUID: appeal.UserID, Valid: true},
+		Note:        params.Note,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot resolve appeal")
+		return
+	}
+	err = qtx.ResolveAppeal(r.Context(), database.ResolveAppealParams{
+		ID:       appeal.ID,
+		ActionID: uuid.NullUUID{UUID: recorded.ID, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot resolve appeal")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, 500, "cannot resolve appeal")
+		return
+	}
+	respondWithJSON(w, 200, moderationActionToResponse(recorded))
+}
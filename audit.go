@@ -0,0 +1,31 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Kinds of audit event.
+const (
+	auditLoginLockout = "login_lockout"
+	auditRoleChange   = "role_change"
+)
+
+// recordAuditEvent keeps a permanent record of a security relevant event
+// caused by request r. It is also logged, so failing to store it is not
+// fatal to the request.
+func (cfg *apiConfig) recordAuditEvent(r *http.Request, kind string, userID uuid.NullUUID, detail string) {
+	log.Printf("Audit %s: %s", kind, detail)
+	err := cfg.db_query.CreateAuditEvent(r.Context(), database.CreateAuditEventParams{
+		Kind:      kind,
+		UserID:    userID,
+		IpAddress: cfg.clientIP(r),
+		Detail:    detail,
+	})
+	if err != nil {
+		log.Printf("Error recording audit event: %s", err)
+	}
+}
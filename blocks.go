@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// blockUser blocks the {userID} user for the caller and ends any follows
+// between the two. Blocked users cannot see, reply to, mention or follow the
+// blocker, and the blocker stops seeing their chirps too.
+func (cfg *apiConfig) blockUser(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorize(w, r, auth.ScopeFollowsWrite)
+	if !ok {
+		return
+	}
+	blocked, ok := cfg.pathUser(w, r)
+	if !ok {
+		return
+	}
+	if blocked == jwtUser {
+		respondWithError(w, 400, "cannot block yourself")
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "cannot block user")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	err = qtx.BlockUser(r.Context(), database.BlockUserParams{BlockerID: jwtUser, BlockedID: blocked})
+	if err != nil {
+		respondWithError(w, 500, "cannot block user")
+		return
+	}
+	err = qtx.DeleteFollowsBetween(r.Context(), database.DeleteFollowsBetweenParams{FollowerID: jwtUser, FolloweeID: blocked})
+	if err != nil {
+		respondWithError(w, 500, "cannot block user")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, 500, "cannot block user")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) unblockUser(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorize(w, r, auth.ScopeFollowsWrite)
+	if !ok {
+		return
+	}
+	blocked, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID format")
+		return
+	}
+	err = cfg.db_query.UnblockUser(r.Context(), database.UnblockUserParams{BlockerID: jwtUser, BlockedID: blocked})
+	if err != nil {
+		respondWithError(w, 500, "cannot unblock user")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+// muteUser leaves the {userID} user's chirps out of the caller's feeds and
+// stops their mentions notifying the caller. Unlike a block, it changes
+// nothing the muted user can see or do.
+func (cfg *apiConfig) muteUser(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorize(w, r, auth.ScopeFollowsWrite)
+	if !ok {
+		return
+	}
+	muted, ok := cfg.pathUser(w, r)
+	if !ok {
+		return
+	}
+	if muted == jwtUser {
+		respondWithError(w, 400, "cannot mute yourself")
+		return
+	}
+	err := cfg.db_query.MuteUser(r.Context(), database.MuteUserParams{MuterID: jwtUser, MutedID: muted})
+	if err != nil {
+		respondWithError(w, 500, "cannot mute user")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) unmuteUser(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorize(w, r, auth.ScopeFollowsWrite)
+	if !ok {
+		return
+	}
+	muted, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID format")
+		return
+	}
+	err = cfg.db_query.UnmuteUser(r.Context(), database.UnmuteUserParams{MuterID: jwtUser, MutedID: muted})
+	if err != nil {
+		respondWithError(w, 500, "cannot unmute user")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+// isBlockedBy reports whether author has blocked viewer. Anonymous viewers
+// are never blocked.
+func (cfg *apiConfig) isBlockedBy(ctx context.Context, author uuid.UUID, viewer uuid.NullUUID) (bool, error) {
+	if !viewer.Valid || viewer.UUID == author {
+		return false, nil
+	}
+	return cfg.db_query.IsBlocked(ctx, database.IsBlockedParams{BlockerID: author, BlockedID: viewer.UUID})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const usage = `usage:
+  chirpy                          run the server
+  chirpy bootstrap-admin <email>  make the account with <email> the first admin`
+
+// runCommand runs the command line command in args, returning the exit
+// status.
+func runCommand(args []string) int {
+	var err error
+	switch {
+	case args[0] == "bootstrap-admin" && len(args) == 2:
+		err = bootstrapAdmin(args[1])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "chirpy %s: %s\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// bootstrapAdmin makes an existing account the first admin. Once there is an
+// admin, roles are managed through the admin API instead.
+func bootstrapAdmin(email string) error {
+	db, err := sql.Open("postgres", os.Getenv("DB_URL"))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := database.New(tx)
+
+	admins, err := qtx.CountUsersWithRole(ctx, auth.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if admins > 0 {
+		return errors.New("an admin already exists; use PUT /admin/users/{userID}/role")
+	}
+	user, err := qtx.GetUser(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("no account with email %s", email)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = qtx.SetUserRole(ctx, database.SetUserRoleParams{ID: user.ID, Role: auth.RoleAdmin})
+	if err != nil {
+		return err
+	}
+	err = qtx.CreateAuditEvent(ctx, database.CreateAuditEventParams{
+		Kind:   auditRoleChange,
+		UserID: uuid.NullUUID{UUID: user.ID, Valid: true},
+		Detail: fmt.Sprintf("%s bootstrapped as the first admin from the command line", user.ID),
+	})
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fmt.Printf("%s is now an admin\n", user.Email)
+	return nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/aklantan/chirpy/internal/mailer"
+)
+
+const (
+	maxEmailLength = 254
+	// maxVerificationEmailsPerHour stops verification emails from being used
+	// to flood someone's inbox
+	maxVerificationEmailsPerHour = 3
+)
+
+// validEmail reports whether email is a bare address such as
+// allan@example.com, without a display name or comments.
+func validEmail(email string) bool {
+	if len(email) > maxEmailLength {
+		return false
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return false
+	}
+	domain := email[strings.LastIndex(email, "@")+1:]
+	return strings.Contains(domain, ".")
+}
+
+// issueEmailVerification creates a token proving that user controls email,
+// invalidating any earlier ones, and returns the message that delivers it.
+// The message should only be sent once q's transaction has committed.
+func issueEmailVerification(ctx context.Context, q *database.Queries, user database.User, email string) (mailer.Message, error) {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return mailer.Message{}, err
+	}
+	err = q.InvalidateEmailVerifications(ctx, user.ID)
+	if err != nil {
+		return mailer.Message{}, err
+	}
+	err = q.CreateEmailVerification(ctx, database.CreateEmailVerificationParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		Email:     email,
+	})
+	if err != nil {
+		return mailer.Message{}, err
+	}
+	return mailer.Message{
+		To:      email,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf("To confirm that this is the email address of the Chirpy account @%s, "+
+			"use this verification token within the next day:\n\n%s\n\n"+
+			"If you did not ask for this, you can ignore this email.\n",
+			user.Handle, token),
+	}, nil
+}
+
+// emailChangeNotice warns the old address of an account that a change of
+// email was requested, in case it was not the owner who asked.
+func emailChangeNotice(user database.User) mailer.Message {
+	return mailer.Message{
+		To:      user.Email,
+		Subject: "Your Chirpy email address is being changed",
+		Body: fmt.Sprintf("Someone asked to change the email address of the Chirpy account @%s. "+
+			"The change takes effect once the new address is verified.\n\n"+
+			"If it was not you, reset your password straight away.\n",
+			user.Handle),
+	}
+}
+
+// verifyEmail consumes a verification token. For a new account it marks the
+// account verified; for an email change it switches the account to the new
+// address.
+func (cfg *apiConfig) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token string `json:"token"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "cannot verify email")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	verification, err := qtx.UseEmailVerification(r.Context(), auth.HashToken(params.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 400, "invalid or expired verification token")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "cannot verify email")
+		return
+	}
+	dbUser, err := qtx.VerifyEmail(r.Context(), database.VerifyEmailParams{ID: verification.UserID, Email: verification.Email})
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "email already taken")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "cannot verify email")
+		return
+	}
+	err = qtx.InvalidateEmailVerifications(r.Context(), dbUser.ID)
+	if err != nil {
+		respondWithError(w, 500, "cannot verify email")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, 500, "cannot verify email")
+		return
+	}
+	respondWithJSON(w, 200, User{
+		ID:            dbUser.ID,
+		CreatedAt:     dbUser.CreatedAt,
+		UpdatedAt:     dbUser.UpdatedAt,
+		Email:         dbUser.Email,
+		Handle:        dbUser.Handle,
+		EmailVerified: dbUser.VerifiedAt.Valid,
+	})
+}
+
+// resendEmailVerification sends a new verification token for the address
+// the account is waiting to verify: its own if it has not been verified yet,
+// or the new one it asked to change to.
+func (cfg *apiConfig) resendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorizeSession(w, r)
+	if !ok {
+		return
+	}
+	dbUser, err := cfg.db_query.GetUserByID(r.Context(), jwtUser)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve user")
+		return
+	}
+	// a verified user may be waiting to verify a new address
+	email := dbUser.Email
+	pending, err := cfg.db_query.GetPendingVerificationEmail(r.Context(), jwtUser)
+	if err == nil {
+		email = pending
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 500, "cannot send verification email")
+		return
+	}
+	if dbUser.VerifiedAt.Valid && email == dbUser.Email {
+		respondWithError(w, 400, "email is already verified")
+		return
+	}
+	recent, err := cfg.db_query.CountRecentEmailVerifications(r.Context(), jwtUser)
+	if err != nil {
+		respondWithError(w, 500, "cannot send verification email")
+		return
+	}
+	if recent >= maxVerificationEmailsPerHour {
+		respondWithError(w, 429, "too many verification emails, try again later")
+		return
+	}
+	msg, err := issueEmailVerification(r.Context(), cfg.db_query, dbUser, email)
+	if err != nil {
+		respondWithError(w, 500, "cannot send verification email")
+		return
+	}
+	cfg.sendMail(msg)
+	respondWithJSON(w, 202, nil)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidEmail(t *testing.T) {
+	for email, want := range map[string]bool{
+		"allan@example.com":                       true,
+		"allan.t+chirpy@mail.example.co.uk":       true,
+		"allan":                                   false,
+		"allan@localhost":                         false,
+		"Allan <allan@example.com>":               false,
+		"<allan@example.com>":                     false,
+		" allan@example.com":                      false,
+		"allan@example.com, boots@example.com":    false,
+		strings.Repeat("a", 242) + "@example.com": true,
+		strings.Repeat("a", 243) + "@example.com": false,
+	} {
+		if got := validEmail(email); got != want {
+			t.Errorf("validEmail(%q) = %v, want %v", email, got, want)
+		}
+	}
+}
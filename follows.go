@@ -0,0 +1,200 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type followResponse struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// pathUser resolves the {userID} path value to an existing user.
+func (cfg *apiConfig) pathUser(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID format")
+		return uuid.Nil, false
+	}
+	_, err = cfg.db_query.GetUserByID(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "user not found")
+		return uuid.Nil, false
+	}
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve user")
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func (cfg *apiConfig) followUser(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorize(w, r, auth.ScopeFollowsWrite)
+	if !ok {
+		return
+	}
+	followee, ok := cfg.pathUser(w, r)
+	if !ok {
+		return
+	}
+	if followee == jwtUser {
+		respondWithError(w, 400, "cannot follow yourself")
+		return
+	}
+	blocked, err := cfg.isBlockedBy(r.Context(), followee, uuid.NullUUID{UUID: jwtUser, Valid: true})
+	if err != nil {
+		respondWithError(w, 500, "cannot follow user")
+		return
+	}
+	if blocked {
+		respondWithError(w, 403, "cannot follow this user")
+		return
+	}
+	err = cfg.db_query.FollowUser(r.Context(), database.FollowUserParams{FollowerID: jwtUser, FolloweeID: followee})
+	if err != nil {
+		respondWithError(w, 500, "cannot follow user")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) unfollowUser(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorize(w, r, auth.ScopeFollowsWrite)
+	if !ok {
+		return
+	}
+	followee, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID format")
+		return
+	}
+	err = cfg.db_query.UnfollowUser(r.Context(), database.UnfollowUserParams{FollowerID: jwtUser, FolloweeID: followee})
+	if err != nil {
+		respondWithError(w, 500, "cannot unfollow user")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) getFollowers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.pathUser(w, r)
+	if !ok {
+		return
+	}
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	if err := page.checkOrder(true); err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	afterCreatedAt, afterID := page.afterCursor()
+	rows, err := cfg.db_query.ListFollowers(r.Context(), database.ListFollowersParams{
+		UserID:         userID,
+		AfterCreatedAt: afterCreatedAt,
+		AfterID:        afterID,
+		Limit:          page.Limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot list followers")
+		return
+	}
+
+	follows := []followResponse{}
+	for _, row := range rows {
+		follows = append(follows, followResponse{UserID: row.UserID, FollowedAt: row.CreatedAt})
+	}
+	respondWithFollowPage(w, r, follows, page.Limit)
+}
+
+func (cfg *apiConfig) getFollowing(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.pathUser(w, r)
+	if !ok {
+		return
+	}
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	if err := page.checkOrder(true); err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	afterCreatedAt, afterID := page.afterCursor()
+	rows, err := cfg.db_query.ListFollowing(r.Context(), database.ListFollowingParams{
+		UserID:         userID,
+		AfterCreatedAt: afterCreatedAt,
+		AfterID:        afterID,
+		Limit:          page.Limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot list followed users")
+		return
+	}
+
+	follows := []followResponse{}
+	for _, row := range rows {
+		follows = append(follows, followResponse{UserID: row.UserID, FollowedAt: row.CreatedAt})
+	}
+	respondWithFollowPage(w, r, follows, page.Limit)
+}
+
+func respondWithFollowPage(w http.ResponseWriter, r *http.Request, follows []followResponse, limit int32) {
+	if len(follows) > int(limit) {
+		follows = follows[:limit]
+		last := follows[len(follows)-1]
+		setNextLink(w, r, chirpCursor{CreatedAt: last.FollowedAt, ID: last.UserID, Desc: true})
+	}
+	respondWithJSON(w, 200, follows)
+}
+
+// getTimeline returns the newest chirps from the accounts the caller follows.
+func (cfg *apiConfig) getTimeline(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorize(w, r, auth.ScopeChirpsRead)
+	if !ok {
+		return
+	}
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	if err := page.checkOrder(true); err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	afterCreatedAt, afterID := page.afterCursor()
+	chirps, err := cfg.db_query.ListTimeline(r.Context(), database.ListTimelineParams{
+		UserID:         jwtUser,
+		AfterCreatedAt: afterCreatedAt,
+		AfterID:        afterID,
+		Limit:          page.Limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve timeline")
+		return
+	}
+	if len(chirps) > int(page.Limit) {
+		chirps = chirps[:page.Limit]
+		last := chirps[len(chirps)-1]
+		setNextLink(w, r, chirpCursor{CreatedAt: last.CreatedAt, ID: last.ID, Desc: true})
+	}
+
+	responseChirps := cfg.chirpsToResponse(chirps)
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: jwtUser, Valid: true}, responseChirps)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve chirp details")
+		return
+	}
+	respondWithJSON(w, 200, responseChirps)
+}
@@ -3,10 +3,12 @@ module github.com/aklantan/chirpy
 go 1.24.1
 
 require (
+	github.com/golang-jwt/jwt/v5 v5.2.2
 	github.com/google/uuid v1.6.0
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.10.9
 	golang.org/x/crypto v0.38.0
+	golang.org/x/text v0.25.0
 )
 
-require github.com/golang-jwt/jwt/v5 v5.2.2 // indirect
+require golang.org/x/sys v0.33.0 // indirect
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/aklantan/chirpy/internal/tags"
+	"github.com/google/uuid"
+)
+
+const (
+	// trending compares how often a tag was used in the recent window with
+	// its rate over the baseline window before it
+	trendingRecentWindow   = time.Hour
+	trendingBaselineWindow = 24 * time.Hour
+	trendingCacheTTL       = time.Minute
+	maxTrendingTags        = 50
+)
+
+type trendingTag struct {
+	Tag        string  `json:"tag"`
+	RecentUses int64   `json:"recent_uses"`
+	Score      float64 `json:"score"`
+}
+
+// trendingCache keeps the last trending computation so that GET
+// /api/tags/trending only hits the database once per trendingCacheTTL.
+type trendingCache struct {
+	mu         sync.Mutex
+	computedAt time.Time
+	tags       []trendingTag
+}
+
+// saveChirpTags makes the hashtags recorded for a chirp match the ones in
+// body. Tags the chirp already had keep the time they were first used, so
+// editing a chirp does not count as using its tags again. Callers run it in
+// the same transaction that writes the body.
+func saveChirpTags(ctx context.Context, q *database.Queries, chirpID uuid.UUID, body string) error {
+	keep := []uuid.UUID{}
+	for _, name := range tags.Extract(body) {
+		tag, err := q.UpsertTag(ctx, name)
+		if err != nil {
+			return err
+		}
+		err = q.AddChirpTag(ctx, database.AddChirpTagParams{ChirpID: chirpID, TagID: tag.ID})
+		if err != nil {
+			return err
+		}
+		keep = append(keep, tag.ID)
+	}
+	return q.DeleteStaleChirpTags(ctx, database.DeleteStaleChirpTagsParams{ChirpID: chirpID, KeepTagIds: keep})
+}
+
+func (cfg *apiConfig) getTagChirps(w http.ResponseWriter, r *http.Request) {
+	tag := tags.Normalize(r.PathValue("tag"))
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	if err := page.checkOrder(true); err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	afterCreatedAt, afterID := page.afterCursor()
+	viewer := cfg.viewer(r)
+	chirps, err := cfg.db_query.ListTagChirps(r.Context(), database.ListTagChirpsParams{
+		Tag:            tag,
+		AfterCreatedAt: afterCreatedAt,
+		AfterID:        afterID,
+		ViewerID:       viewer,
+		Limit:          page.Limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve chirps")
+		return
+	}
+	if len(chirps) > int(page.Limit) {
+		chirps = chirps[:page.Limit]
+		last := chirps[len(chirps)-1]
+		setNextLink(w, r, chirpCursor{CreatedAt: last.CreatedAt, ID: last.ID, Desc: true})
+	}
+
+	responseChirps := cfg.chirpsToResponse(chirps)
+	err = cfg.hydrateChirps(r.Context(), viewer, responseChirps)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve chirp details")
+		return
+	}
+	respondWithJSON(w, 200, responseChirps)
+}
+
+func (cfg *apiConfig) getTrendingTags(w http.ResponseWriter, r *http.Request) {
+	limit := 10
+	if param := r.URL.Query().Get("limit"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 1 || n > maxTrendingTags {
+			respondWithError(w, 400, "limit must be between 1 and "+strconv.Itoa(maxTrendingTags))
+			return
+		}
+		limit = n
+	}
+
+	trending, err := cfg.trendingTags(r.Context())
+	if err != nil {
+		respondWithError(w, 500, "cannot compute trending tags")
+		return
+	}
+	if len(trending) > limit {
+		trending = trending[:limit]
+	}
+	respondWithJSON(w, 200, trending)
+}
+
+// trendingTags returns every recently used tag ranked by velocity, recomputing
+// the ranking at most once per trendingCacheTTL.
+func (cfg *apiConfig) trendingTags(ctx context.Context) ([]trendingTag, error) {
+	cfg.trending.mu.Lock()
+	defer cfg.trending.mu.Unlock()
+
+	now := time.Now()
+	if cfg.trending.tags != nil && now.Sub(cfg.trending.computedAt) < trendingCacheTTL {
+		return cfg.trending.tags, nil
+	}
+
+	rows, err := cfg.db_query.CountTagUses(ctx, database.CountTagUsesParams{
+		RecentSeconds: int32(trendingRecentWindow.Seconds()),
+		WindowSeconds: int32((trendingRecentWindow + trendingBaselineWindow).Seconds()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	trending := []trendingTag{}
+	for _, row := range rows {
+		trending = append(trending, trendingTag{
+			Tag:        row.Name,
+			RecentUses: row.RecentUses,
+			Score:      trendingScore(row.RecentUses, row.BaselineUses),
+		})
+	}
+	sort.Slice(trending, func(i, j int) bool {
+		if trending[i].Score != trending[j].Score {
+			return trending[i].Score > trending[j].Score
+		}
+		if trending[i].RecentUses != trending[j].RecentUses {
+			return trending[i].RecentUses > trending[j].RecentUses
+		}
+		return trending[i].Tag < trending[j].Tag
+	})
+
+	cfg.trending.tags = trending
+	cfg.trending.computedAt = now
+	return trending, nil
+}
+
+// trendingScore measures how far a tag's recent uses are above what its
+// baseline rate predicts, in units of the expected count's square root (as for
+// a Poisson rate), so busy tags need a proportionally bigger jump to trend.
+func trendingScore(recent, baseline int64) float64 {
+	expected := float64(baseline) * float64(trendingRecentWindow) / float64(trendingBaselineWindow)
+	return (float64(recent) - expected) / math.Sqrt(expected+1)
+}
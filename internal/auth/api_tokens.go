@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// APITokenPrefix starts every personal API token, which tells them apart from
+// JWTs and makes leaked tokens easy to spot in logs and code scanners.
+const APITokenPrefix = "chirpy_pat_"
+
+// Scopes that personal API tokens can be granted. Sessions from a password
+// login are not limited by scopes.
+const (
+	ScopeChirpsRead         = "chirps:read"
+	ScopeChirpsWrite        = "chirps:write"
+	ScopeFollowsWrite       = "follows:write"
+	ScopeProfileWrite       = "profile:write"
+	ScopeNotificationsRead  = "notifications:read"
+	ScopeNotificationsWrite = "notifications:write"
+)
+
+var scopes = map[string]bool{
+	ScopeChirpsRead:         true,
+	ScopeChirpsWrite:        true,
+	ScopeFollowsWrite:       true,
+	ScopeProfileWrite:       true,
+	ScopeNotificationsRead:  true,
+	ScopeNotificationsWrite: true,
+}
+
+// ValidScope reports whether scope can be granted to an API token.
+func ValidScope(scope string) bool {
+	return scopes[scope]
+}
+
+// MakeAPIToken returns a new random personal API token.
+func MakeAPIToken() (string, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", errors.New("unable to generate data")
+	}
+	return APITokenPrefix + hex.EncodeToString(key), nil
+}
+
+// IsAPIToken reports whether a bearer token is a personal API token rather
+// than a JWT.
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// HashToken is the form random secrets such as API tokens, OAuth client
+// secrets and authorization codes are stored and looked up in. They carry 256
+// random bits, so unlike passwords they need no salt or slow hash.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
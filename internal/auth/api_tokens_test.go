@@ -0,0 +1,23 @@
+package auth
+
+import "testing"
+
+func TestAPIToken(t *testing.T) {
+	token, err := MakeAPIToken()
+	if err != nil {
+		t.Fatalf("cannot make API token: %v", err)
+	}
+	if !IsAPIToken(token) {
+		t.Errorf("%q not recognised as an API token", token)
+	}
+	if HashToken(token) != HashToken(token) || HashToken(token) == token {
+		t.Error("hash is not a stable digest of the token")
+	}
+	other, _ := MakeAPIToken()
+	if HashToken(other) == HashToken(token) {
+		t.Error("different tokens share a hash")
+	}
+	if IsAPIToken("eyJhbGciOiJFZERTQSJ9.e30.sig") {
+		t.Error("JWT recognised as an API token")
+	}
+}
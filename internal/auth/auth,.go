@@ -11,68 +11,157 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// HashPassword hashes password with the DefaultPasswordHasher.
 func HashPassword(password string) (string, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		fmt.Printf("%v : cannot hash password\n", err)
-		return "", err
-	}
-	return string(hash), nil
+	return DefaultPasswordHasher.Hash(password)
 }
 
+// CheckPasswordHash checks password against a hash of any supported version,
+// returning ErrPasswordMismatch if it does not match.
 func CheckPasswordHash(hash, password string) error {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	_, err := DefaultPasswordHasher.Verify(hash, password)
+	return err
+}
+
+// Access tokens and 2FA challenge tokens are signed with the same keys, so
+// the issuer claim keeps each kind from being accepted in place of the other.
+const (
+	accessIssuer    = "chirpy"
+	challengeIssuer = "chirpy-2fa"
+)
+
+// Claims are the claims in Chirpy's tokens. Scope and ClientID are only set
+// on access tokens issued to OAuth clients, which may only act within the
+// scopes the user granted them. Role is only set on access tokens from a
+// login, so OAuth clients can never act with a moderator's or admin's role.
+type Claims struct {
+	jwt.RegisteredClaims
+	// Scope is a space separated list, as in OAuth 2.0
+	Scope    string `json:"scope,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	Role     string `json:"role,omitempty"`
+}
+
+func (c *Claims) UserID() (uuid.UUID, error) {
+	return uuid.Parse(c.Subject)
+}
+
+// Delegated reports whether the token was issued to an OAuth client.
+func (c *Claims) Delegated() bool {
+	return c.ClientID != ""
+}
+
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// MakeJWT issues an access token for a user with role. The role is trusted
+// until the token expires, so a change of role takes effect once the user
+// next logs in or refreshes their token.
+func MakeJWT(userID uuid.UUID, role string, keys *KeySet, expiresIn time.Duration) (string, error) {
+	claims := newClaims(userID, accessIssuer, expiresIn)
+	claims.Role = role
+	return makeJWT(claims, keys)
+}
+
+// MakeDelegatedJWT issues an access token that lets an OAuth client act for
+// userID within scopes.
+func MakeDelegatedJWT(userID uuid.UUID, clientID string, scopes []string, keys *KeySet, expiresIn time.Duration) (string, error) {
+	claims := newClaims(userID, accessIssuer, expiresIn)
+	claims.ClientID = clientID
+	claims.Scope = strings.Join(scopes, " ")
+	return makeJWT(claims, keys)
+}
+
+// ValidateJWT returns the user an access token from a login was issued to.
+// Tokens issued to OAuth clients are refused, since their scopes would be
+// ignored; use ParseJWT where those are accepted.
+func ValidateJWT(tokenString string, keys *KeySet) (uuid.UUID, error) {
+	claims, err := ParseJWT(tokenString, keys)
 	if err != nil {
-		fmt.Printf("%v : incorrect password\n", err)
-		return err
+		return uuid.Nil, err
+	}
+	if claims.Delegated() {
+		return uuid.Nil, errors.New("token was issued to an OAuth client")
+	}
+	return claims.UserID()
+}
+
+// ParseJWT validates any access token and returns its claims.
+func ParseJWT(tokenString string, keys *KeySet) (*Claims, error) {
+	return validateJWT(tokenString, accessIssuer, keys)
+}
+
+// MakeChallengeToken issues the token a user exchanges, together with a
+// second factor, for an access token once their password has been checked.
+// Each token has its own ID so that callers can limit how often it is used.
+func MakeChallengeToken(userID uuid.UUID, keys *KeySet, expiresIn time.Duration) (string, error) {
+	claims := newClaims(userID, challengeIssuer, expiresIn)
+	claims.ID = uuid.NewString()
+	return makeJWT(claims, keys)
+}
 
+// ValidateChallengeToken returns the user a challenge token was issued to and
+// the token's ID.
+func ValidateChallengeToken(tokenString string, keys *KeySet) (uuid.UUID, string, error) {
+	claims, err := validateJWT(tokenString, challengeIssuer, keys)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	if claims.ID == "" {
+		return uuid.Nil, "", errors.New("challenge token has no ID")
 	}
-	return nil
+	userID, err := claims.UserID()
+	return userID, claims.ID, err
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-	claims := jwt.RegisteredClaims{
-		Issuer:    "chirpy",
+func newClaims(userID uuid.UUID, issuer string, expiresIn time.Duration) *Claims {
+	return &Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    issuer,
 		IssuedAt:  jwt.NewNumericDate(time.Now()),
 		NotBefore: jwt.NewNumericDate(time.Now()),
 		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
 		Subject:   userID.String(),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(tokenSecret))
+	}}
+}
+
+func makeJWT(claims *Claims, keys *KeySet) (string, error) {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(keys.signing.Algorithm), claims)
+	token.Header["kid"] = keys.signing.ID
+	signedToken, err := token.SignedString(keys.signing.private)
 	if err != nil {
 		fmt.Printf("%v : cannot sign token\n", err)
 		return "", err
 	}
-	fmt.Println(signedToken, err)
 	return signedToken, nil
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	claims := &jwt.RegisteredClaims{}
+// validateJWT only accepts tokens signed by a key in keys with that key's own
+// algorithm. In particular "none" and HMAC tokens are refused, so a public
+// key can never be used as a shared secret.
+func validateJWT(tokenString, issuer string, keys *KeySet) (*Claims, error) {
+	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(tokenSecret), nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keys.lookup(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.Algorithm {
+			return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+		}
+		return key.public, nil
+	}, jwt.WithIssuer(issuer), jwt.WithValidMethods([]string{"EdDSA", "RS256"}))
 	if err != nil {
 		fmt.Printf("%v : cannot parse token\n", err)
-		return uuid.Nil, err
+		return nil, err
 	}
 	if !token.Valid {
-		return uuid.Nil, fmt.Errorf("invalid token")
-	}
-	//expired := claims.ExpiresAt
-	//if expired < time.Now() {
-	//	return uuid.Nil, fmt.Errorf("expired token")
-	//}
-	userID := claims.Subject
-	if err != nil {
-		fmt.Printf("%v : incorrect token\n", err)
-		return uuid.Nil, err
+		return nil, fmt.Errorf("invalid token")
 	}
-	return uuid.Parse(userID)
+	return claims, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
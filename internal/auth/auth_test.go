@@ -20,14 +20,38 @@ func TestHashPassword(t *testing.T) {
 }
 
 func TestJWT(t *testing.T){
-	secret := "walrider"
+	keys := testKeySet(t)
 	testID := uuid.New()
-	token, err := MakeJWT(testID,secret,30*time.Minute)
+	token, err := MakeJWT(testID,RoleUser,keys,30*time.Minute)
 	if err != nil {
 		t.Errorf("cannot make JWT : %v ",err)
 	}
-	_, err = ValidateJWT(token,secret)
+	_, err = ValidateJWT(token,keys)
 	if err != nil{
 		t.Errorf("UUIDs do not match : %v", err)
 	}
-}
\ No newline at end of file
+}
+
+func TestChallengeTokenIsNotAnAccessToken(t *testing.T) {
+	keys := testKeySet(t)
+	testID := uuid.New()
+	challenge, err := MakeChallengeToken(testID, keys, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("cannot make challenge token : %v ", err)
+	}
+	if _, err := ValidateJWT(challenge, keys); err == nil {
+		t.Error("challenge token accepted as an access token")
+	}
+	userID, challengeID, err := ValidateChallengeToken(challenge, keys)
+	if err != nil || userID != testID {
+		t.Errorf("challenge token rejected : %v", err)
+	}
+	other, _ := MakeChallengeToken(testID, keys, 5*time.Minute)
+	if _, otherID, _ := ValidateChallengeToken(other, keys); challengeID == "" || otherID == challengeID {
+		t.Errorf("challenge tokens share ID %q", challengeID)
+	}
+	access, _ := MakeJWT(testID, RoleUser, keys, 5*time.Minute)
+	if _, _, err := ValidateChallengeToken(access, keys); err == nil {
+		t.Error("access token accepted as a challenge token")
+	}
+}
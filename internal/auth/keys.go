@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// minRSABits is the smallest RSA modulus accepted for RS256 keys.
+const minRSABits = 2048
+
+// SigningKey is a key that tokens are signed and verified with. Keys loaded
+// from a public key can only verify. The ID is the key's RFC 7638 thumbprint
+// and is sent as the kid header of every token it signs.
+type SigningKey struct {
+	ID        string
+	Algorithm string
+	public    crypto.PublicKey
+	private   crypto.Signer
+}
+
+// CanSign reports whether the key includes its private half.
+func (k *SigningKey) CanSign() bool {
+	return k.private != nil
+}
+
+// GenerateSigningKey returns a new random Ed25519 key.
+func GenerateSigningKey() (*SigningKey, error) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return newSigningKey(private)
+}
+
+// ParseKeyPEM reads an Ed25519 or RSA key from PEM. Private keys may be
+// PKCS #8 or PKCS #1, public keys PKIX or PKCS #1.
+func ParseKeyPEM(data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return newSigningKey(key)
+}
+
+func newSigningKey(key any) (*SigningKey, error) {
+	k := &SigningKey{}
+	switch key := key.(type) {
+	case ed25519.PrivateKey:
+		k.Algorithm, k.public, k.private = "EdDSA", key.Public(), key
+	case ed25519.PublicKey:
+		k.Algorithm, k.public = "EdDSA", key
+	case *rsa.PrivateKey:
+		k.Algorithm, k.public, k.private = "RS256", key.Public(), key
+	case *rsa.PublicKey:
+		k.Algorithm, k.public = "RS256", key
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	if public, ok := k.public.(*rsa.PublicKey); ok && public.N.BitLen() < minRSABits {
+		return nil, fmt.Errorf("RSA keys must be at least %d bits", minRSABits)
+	}
+	thumbprint, err := json.Marshal(k.jwk().thumbprintMembers())
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(thumbprint)
+	k.ID = base64.RawURLEncoding.EncodeToString(sum[:])
+	return k, nil
+}
+
+// KeySet holds the key new tokens are signed with and every key tokens are
+// still accepted from. To rotate, start signing with a new key while keeping
+// the old one for verification until the tokens it signed have expired.
+type KeySet struct {
+	signing *SigningKey
+	keys    map[string]*SigningKey
+}
+
+// NewKeySet returns a set that signs with signing and verifies with it and
+// any of the other keys.
+func NewKeySet(signing *SigningKey, others ...*SigningKey) (*KeySet, error) {
+	if signing == nil || !signing.CanSign() {
+		return nil, errors.New("signing key has no private key")
+	}
+	ks := &KeySet{signing: signing, keys: map[string]*SigningKey{signing.ID: signing}}
+	for _, key := range others {
+		ks.keys[key.ID] = key
+	}
+	return ks, nil
+}
+
+// lookup returns the key a token's kid header names.
+func (ks *KeySet) lookup(kid string) (*SigningKey, bool) {
+	key, ok := ks.keys[kid]
+	return key, ok
+}
+
+// JWK is the public half of a key as published in a JWK Set (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS lists the public keys of every key in the set, ordered by kid.
+func (ks *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		set.Keys = append(set.Keys, key.jwk())
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+func (k *SigningKey) jwk() JWK {
+	jwk := JWK{Use: "sig", Alg: k.Algorithm, Kid: k.ID}
+	switch public := k.public.(type) {
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	}
+	return jwk
+}
+
+// thumbprintMembers are the required members of the JWK that RFC 7638 hashes.
+// encoding/json writes map keys in sorted order, as the RFC requires.
+func (jwk JWK) thumbprintMembers() map[string]string {
+	if jwk.Kty == "RSA" {
+		return map[string]string{"e": jwk.E, "kty": jwk.Kty, "n": jwk.N}
+	}
+	return map[string]string{"crv": jwk.Crv, "kty": jwk.Kty, "x": jwk.X}
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func testKeySet(t *testing.T) *KeySet {
+	t.Helper()
+	key, err := GenerateSigningKey()
+	if err != nil {
+		t.Fatalf("cannot generate key: %v", err)
+	}
+	keys, err := NewKeySet(key)
+	if err != nil {
+		t.Fatalf("cannot build key set: %v", err)
+	}
+	return keys
+}
+
+// RFC 7638 section 3.1
+func TestKeyIDIsJWKThumbprint(t *testing.T) {
+	n, _ := base64.RawURLEncoding.DecodeString("0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw")
+	key, err := newSigningKey(&rsa.PublicKey{N: new(big.Int).SetBytes(n), E: 65537})
+	if err != nil {
+		t.Fatalf("cannot load key: %v", err)
+	}
+	if key.ID != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("got kid %s", key.ID)
+	}
+	if key.CanSign() {
+		t.Error("public key reported as able to sign")
+	}
+}
+
+func TestParseKeyPEM(t *testing.T) {
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(private)
+	privateKey, err := ParseKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil || !privateKey.CanSign() {
+		t.Fatalf("cannot parse private key: %v", err)
+	}
+	der, _ = x509.MarshalPKIXPublicKey(private.Public())
+	publicKey, err := ParseKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil || publicKey.CanSign() {
+		t.Fatalf("cannot parse public key: %v", err)
+	}
+	if privateKey.ID != publicKey.ID {
+		t.Errorf("private and public halves have different kids")
+	}
+	if _, err := NewKeySet(publicKey); err == nil {
+		t.Error("key set accepted a public key for signing")
+	}
+}
+
+func TestRotatedKeyStillVerifies(t *testing.T) {
+	oldKey, _ := GenerateSigningKey()
+	newKey, _ := GenerateSigningKey()
+	oldKeys, _ := NewKeySet(oldKey)
+	rotated, _ := NewKeySet(newKey, oldKey)
+
+	testID := uuid.New()
+	token, err := MakeJWT(testID, RoleUser, oldKeys, time.Minute)
+	if err != nil {
+		t.Fatalf("cannot make JWT: %v", err)
+	}
+	if userID, err := ValidateJWT(token, rotated); err != nil || userID != testID {
+		t.Errorf("token from the previous key rejected: %v", err)
+	}
+	if _, err := ValidateJWT(token, testKeySet(t)); err == nil {
+		t.Error("token from an unknown key accepted")
+	}
+	if got := len(rotated.JWKS().Keys); got != 2 {
+		t.Errorf("JWKS has %d keys, want 2", got)
+	}
+}
+
+func TestRS256(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("cannot generate RSA key: %v", err)
+	}
+	key, err := newSigningKey(private)
+	if err != nil {
+		t.Fatalf("cannot load key: %v", err)
+	}
+	keys, _ := NewKeySet(key)
+	token, _ := MakeJWT(uuid.New(), RoleUser, keys, time.Minute)
+	if _, err := ValidateJWT(token, keys); err != nil {
+		t.Errorf("RS256 token rejected: %v", err)
+	}
+	jwk := keys.JWKS().Keys[0]
+	if jwk.Kty != "RSA" || jwk.Alg != "RS256" || jwk.E != "AQAB" {
+		t.Errorf("unexpected JWK %+v", jwk)
+	}
+}
+
+func TestValidateJWTRejectsUnexpectedAlgorithms(t *testing.T) {
+	keys := testKeySet(t)
+	claims := jwt.RegisteredClaims{
+		Issuer:    accessIssuer,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		Subject:   uuid.New().String(),
+	}
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+	unsigned.Header["kid"] = keys.signing.ID
+	noneToken, _ := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if _, err := ValidateJWT(noneToken, keys); err == nil {
+		t.Error("alg none accepted")
+	}
+
+	// the classic confusion attack: HMAC keyed with the public key
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	hmac.Header["kid"] = keys.signing.ID
+	hmacToken, _ := hmac.SignedString([]byte(keys.signing.public.(ed25519.PublicKey)))
+	if _, err := ValidateJWT(hmacToken, keys); err == nil {
+		t.Error("HS256 accepted")
+	}
+
+	valid, _ := MakeJWT(uuid.New(), RoleUser, keys, time.Minute)
+	parts := strings.Split(valid, ".")
+	header, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	if !strings.Contains(string(header), `"kid":"`+keys.signing.ID+`"`) {
+		t.Errorf("kid missing from header %s", header)
+	}
+}
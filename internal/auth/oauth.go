@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+)
+
+// MakeOAuthSecret returns a random client secret or authorization code.
+func MakeOAuthSecret() (string, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", errors.New("unable to generate data")
+	}
+	return base64.RawURLEncoding.EncodeToString(key), nil
+}
+
+// PKCEChallenge is the S256 code challenge for verifier (RFC 7636).
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyPKCE checks the code verifier a client sends to the token endpoint
+// against the S256 challenge it sent when the user authorized it.
+func VerifyPKCE(verifier, challenge string) bool {
+	if !validPKCEVerifier(verifier) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(PKCEChallenge(verifier)), []byte(challenge)) == 1
+}
+
+// ValidPKCEChallenge reports whether challenge has the form of an S256 code
+// challenge: a base64url encoded SHA-256 digest.
+func ValidPKCEChallenge(challenge string) bool {
+	decoded, err := base64.RawURLEncoding.DecodeString(challenge)
+	return err == nil && len(decoded) == sha256.Size
+}
+
+// validPKCEVerifier reports whether verifier is 43 to 128 unreserved
+// characters, as RFC 7636 section 4.1 requires.
+func validPKCEVerifier(verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	for _, c := range verifier {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-', c == '.', c == '_', c == '~':
+		default:
+			return false
+		}
+	}
+	return true
+}
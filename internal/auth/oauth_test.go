@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	verifier := "M25iVXpKU3puUjFaYWg3T1NDTDQ2RkhBVTBMWGtRTU1VWnFWcE9Zbg"
+	challenge := "uTqUdQOI1WS53HB9M3ckKHPXIRB4fuKUFDKCvP7wsRY"
+	if !ValidPKCEChallenge(challenge) {
+		t.Error("challenge reported as malformed")
+	}
+	if !VerifyPKCE(verifier, challenge) {
+		t.Error("verifier rejected")
+	}
+	if VerifyPKCE(verifier+"x", challenge) {
+		t.Error("wrong verifier accepted")
+	}
+	if VerifyPKCE("short", PKCEChallenge("short")) {
+		t.Error("verifier under 43 characters accepted")
+	}
+	if VerifyPKCE(strings.Repeat("a", 42)+"!", PKCEChallenge(strings.Repeat("a", 42)+"!")) {
+		t.Error("verifier with a reserved character accepted")
+	}
+}
+
+func TestDelegatedJWT(t *testing.T) {
+	keys := testKeySet(t)
+	testID := uuid.New()
+	token, err := MakeDelegatedJWT(testID, "client-1", []string{ScopeChirpsRead, ScopeChirpsWrite}, keys, time.Minute)
+	if err != nil {
+		t.Fatalf("cannot make JWT: %v", err)
+	}
+	claims, err := ParseJWT(token, keys)
+	if err != nil {
+		t.Fatalf("cannot parse JWT: %v", err)
+	}
+	if !claims.Delegated() || claims.ClientID != "client-1" || len(claims.Scopes()) != 2 {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+	if _, err := ValidateJWT(token, keys); err == nil {
+		t.Error("ValidateJWT accepted a token issued to an OAuth client")
+	}
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// PasswordPolicy is what a new password must satisfy. Following NIST SP
+// 800-63B it only limits length and refuses passwords known from breaches,
+// rather than demanding particular kinds of character.
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	// Breached holds known breached passwords, lower cased
+	Breached map[string]struct{}
+}
+
+// DefaultPasswordPolicy only limits length.
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 8, MaxLength: 128}
+
+// ReadBreachedPasswords reads a list of breached passwords, one per line.
+// Blank lines and lines starting with # are skipped.
+func ReadBreachedPasswords(r io.Reader) (map[string]struct{}, error) {
+	breached := map[string]struct{}{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		breached[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return breached, nil
+}
+
+// Check returns an error describing why password is not allowed, or nil.
+// Lengths are counted in characters, not bytes.
+func (p PasswordPolicy) Check(password string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return fmt.Errorf("password must be at most %d characters", p.MaxLength)
+	}
+	// case is ignored, so "Password1" is refused along with "password1"
+	if _, ok := p.Breached[strings.ToLower(password)]; ok {
+		return fmt.Errorf("password appears in a list of breached passwords")
+	}
+	return nil
+}
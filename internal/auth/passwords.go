@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrPasswordMismatch is returned when a password does not match its hash.
+var ErrPasswordMismatch = errors.New("password does not match")
+
+// Argon2Params are the cost parameters of an Argon2id password hash.
+type Argon2Params struct {
+	// Memory is in KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params follow the OWASP password storage recommendations.
+var DefaultArgon2Params = Argon2Params{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// PasswordHasher hashes new passwords with Argon2id and verifies both those
+// and the bcrypt hashes Chirpy stored before. Hashes are self-describing:
+// Argon2id hashes use the PHC string format, which records the parameters
+// they were made with, so the parameters can be raised without invalidating
+// existing hashes.
+type PasswordHasher struct {
+	Params Argon2Params
+}
+
+// DefaultPasswordHasher is used by HashPassword.
+var DefaultPasswordHasher = &PasswordHasher{Params: DefaultArgon2Params}
+
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.Params.SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", errors.New("unable to generate data")
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Params.Iterations, h.Params.Memory, h.Params.Parallelism, h.Params.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Params.Memory, h.Params.Iterations, h.Params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify checks password against hash, returning ErrPasswordMismatch if it
+// is wrong. When it is right, rehash reports whether hash was made with a
+// legacy algorithm or different parameters and should be replaced by a new
+// hash of password.
+func (h *PasswordHasher) Verify(hash, password string) (rehash bool, err error) {
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, ErrPasswordMismatch
+		}
+		if err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	params, salt, key, err := decodeArgon2Hash(hash)
+	if err != nil {
+		return false, err
+	}
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return false, ErrPasswordMismatch
+	}
+	// the salt length is not recorded, but is also not worth a rehash
+	params.SaltLength = h.Params.SaltLength
+	return params != h.Params, nil
+}
+
+func decodeArgon2Hash(hash string) (Argon2Params, []byte, []byte, error) {
+	params := Argon2Params{}
+	fields := strings.Split(hash, "$")
+	if len(fields) != 6 {
+		return params, nil, nil, errors.New("malformed argon2id hash")
+	}
+	var version int
+	_, err := fmt.Sscanf(fields[2], "v=%d", &version)
+	if err != nil {
+		return params, nil, nil, errors.New("malformed argon2id hash")
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+	_, err = fmt.Sscanf(fields[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		return params, nil, nil, errors.New("malformed argon2id hash")
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(fields[4])
+	if err != nil {
+		return params, nil, nil, errors.New("malformed argon2id hash")
+	}
+	key, err := base64.RawStdEncoding.DecodeString(fields[5])
+	if err != nil {
+		return params, nil, nil, errors.New("malformed argon2id hash")
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testArgon2Params = Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestPasswordHasher(t *testing.T) {
+	hasher := &PasswordHasher{Params: testArgon2Params}
+	hash, err := hasher.Hash("hangGlider")
+	if err != nil {
+		t.Fatalf("cannot hash password: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("unexpected hash format %q", hash)
+	}
+	rehash, err := hasher.Verify(hash, "hangGlider")
+	if err != nil || rehash {
+		t.Errorf("Verify(correct password) = %v, %v, want false, nil", rehash, err)
+	}
+	_, err = hasher.Verify(hash, "hangglider")
+	if !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("Verify(wrong password) = %v, want ErrPasswordMismatch", err)
+	}
+
+	stronger := &PasswordHasher{Params: testArgon2Params}
+	stronger.Params.Iterations = 2
+	rehash, err = stronger.Verify(hash, "hangGlider")
+	if err != nil || !rehash {
+		t.Errorf("Verify with new parameters = %v, %v, want true, nil", rehash, err)
+	}
+}
+
+func TestPasswordHasherLegacyBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("hangGlider"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hasher := &PasswordHasher{Params: testArgon2Params}
+	rehash, err := hasher.Verify(string(legacy), "hangGlider")
+	if err != nil || !rehash {
+		t.Errorf("Verify(bcrypt hash) = %v, %v, want true, nil", rehash, err)
+	}
+	_, err = hasher.Verify(string(legacy), "wrong")
+	if !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("Verify(bcrypt hash, wrong password) = %v, want ErrPasswordMismatch", err)
+	}
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	breached, err := ReadBreachedPasswords(strings.NewReader("# top passwords\npassword1\n\nQwertyuiop\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := PasswordPolicy{MinLength: 8, MaxLength: 16, Breached: breached}
+	for password, ok := range map[string]bool{
+		"hangGlider":               true,
+		"short":                    false,
+		"ünïcödé!":                 true,
+		"much too long a password": false,
+		"Password1":                false,
+		"qwertyuiop":               false,
+	} {
+		if err := policy.Check(password); (err == nil) != ok {
+			t.Errorf("Check(%q) = %v, want ok %v", password, err, ok)
+		}
+	}
+}
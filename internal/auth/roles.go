@@ -0,0 +1,28 @@
+package auth
+
+// Roles a user can have. Each role can do everything the roles before it
+// can: moderators work the moderation queue and admins also run the site.
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var roleRanks = map[string]int{
+	RoleUser:      0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+// ValidRole reports whether role is a known role.
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// HasRole reports whether a user with role may do what required allows. An
+// unknown or empty role, as in tokens issued before roles existed, is
+// treated as RoleUser.
+func HasRole(role, required string) bool {
+	return roleRanks[role] >= roleRanks[required]
+}
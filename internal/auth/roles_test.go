@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHasRole(t *testing.T) {
+	cases := []struct {
+		role, required string
+		want           bool
+	}{
+		{RoleAdmin, RoleModerator, true},
+		{RoleModerator, RoleModerator, true},
+		{RoleModerator, RoleAdmin, false},
+		{RoleUser, RoleModerator, false},
+		{"", RoleUser, true},
+		{"", RoleModerator, false},
+		{"superuser", RoleModerator, false},
+	}
+	for _, c := range cases {
+		if got := HasRole(c.role, c.required); got != c.want {
+			t.Errorf("HasRole(%q, %q) = %v, want %v", c.role, c.required, got, c.want)
+		}
+	}
+}
+
+func TestJWTCarriesRole(t *testing.T) {
+	keys := testKeySet(t)
+	token, err := MakeJWT(uuid.New(), RoleModerator, keys, time.Minute)
+	if err != nil {
+		t.Fatalf("cannot make JWT: %v", err)
+	}
+	claims, err := ParseJWT(token, keys)
+	if err != nil {
+		t.Fatalf("cannot parse JWT: %v", err)
+	}
+	if claims.Role != RoleModerator {
+		t.Errorf("role = %q, want %q", claims.Role, RoleModerator)
+	}
+
+	delegated, _ := MakeDelegatedJWT(uuid.New(), "client-1", []string{ScopeChirpsRead}, keys, time.Minute)
+	claims, err = ParseJWT(delegated, keys)
+	if err != nil {
+		t.Fatalf("cannot parse JWT: %v", err)
+	}
+	if claims.Role != "" {
+		t.Errorf("delegated token carries role %q", claims.Role)
+	}
+}
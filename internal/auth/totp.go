@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238). These are the defaults authenticator apps
+// assume; the provisioning URI spells them out anyway.
+const (
+	totpPeriod = 30 * time.Second
+	totpDigits = 6
+	// totpSkew is how many periods either side of now a code is accepted
+	// for, to allow for clock drift between server and phone
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a new random 160-bit TOTP secret, base32 encoded
+// as authenticator apps expect.
+func GenerateTOTPSecret() (string, error) {
+	key := make([]byte, 20)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", errors.New("unable to generate data")
+	}
+	return totpEncoding.EncodeToString(key), nil
+}
+
+// TOTPProvisioningURI builds the otpauth:// URI that authenticator apps read
+// from a QR code.
+func TOTPProvisioningURI(secret, issuer, account string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// TOTPStep is the time step that t falls in.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(totpPeriod.Seconds())
+}
+
+// ValidateTOTP checks code against secret at time t, allowing totpSkew steps
+// of drift. It returns the step the code matched so that callers can refuse
+// to accept the same code, or an older one, twice.
+func ValidateTOTP(secret, code string, t time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return 0, false
+	}
+	code = strings.TrimSpace(code)
+	if len(code) != totpDigits {
+		return 0, false
+	}
+	now := TOTPStep(t)
+	for step := now - totpSkew; step <= now+totpSkew; step++ {
+		expected := totpCode(key, uint64(step), totpDigits)
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode is the HOTP value (RFC 4226) of key for counter.
+func totpCode(key []byte, counter uint64, digits int) string {
+	mac := hmac.New(sha1.New, key)
+	binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
+
+// GenerateRecoveryCodes returns n random single-use recovery codes formatted
+// as two groups of five hex digits.
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		key := make([]byte, 5)
+		_, err := rand.Read(key)
+		if err != nil {
+			return nil, errors.New("unable to generate data")
+		}
+		code := hex.EncodeToString(key)
+		codes = append(codes, code[:5]+"-"+code[5:])
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode strips the separators and case differences people
+// introduce when typing a recovery code back in.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
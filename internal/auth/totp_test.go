@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B, SHA1 rows
+func TestTOTPCodeRFCVectors(t *testing.T) {
+	key := []byte("12345678901234567890")
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, c := range cases {
+		step := TOTPStep(time.Unix(c.unix, 0))
+		if got := totpCode(key, uint64(step), 8); got != c.code {
+			t.Errorf("T=%d: got %s, want %s", c.unix, got, c.code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("cannot generate secret: %v", err)
+	}
+	key, _ := totpEncoding.DecodeString(secret)
+	now := time.Unix(1700000000, 0)
+	step := TOTPStep(now)
+
+	for _, drift := range []int64{-1, 0, 1} {
+		code := totpCode(key, uint64(step+drift), totpDigits)
+		matched, ok := ValidateTOTP(secret, code, now)
+		if !ok || matched != step+drift {
+			t.Errorf("drift %d: got (%d, %v), want (%d, true)", drift, matched, ok, step+drift)
+		}
+	}
+	if _, ok := ValidateTOTP(secret, totpCode(key, uint64(step+2), totpDigits), now); ok {
+		t.Error("code two steps ahead was accepted")
+	}
+	if _, ok := ValidateTOTP(secret, "12345", now); ok {
+		t.Error("short code was accepted")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil {
+		t.Fatalf("cannot generate recovery codes: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("unexpected recovery code format %q", code)
+		}
+		seen[code] = true
+	}
+	if len(seen) != 10 {
+		t.Errorf("got %d distinct codes, want 10", len(seen))
+	}
+	if got := NormalizeRecoveryCode(" AB12C-3d4E5 "); got != "ab12c3d4e5" {
+		t.Errorf("NormalizeRecoveryCode: got %q", got)
+	}
+}
@@ -0,0 +1,86 @@
+// Package clientip works out which address a request came from, including
+// when the server sits behind reverse proxies.
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Resolver finds the client address of requests. X-Forwarded-For is only
+// believed when the request came from one of the trusted proxies; any client
+// can set the header, so without trusted proxies it is ignored.
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// New returns a Resolver that trusts the proxies in list, a comma-separated
+// list of addresses and CIDR prefixes such as "127.0.0.1,10.0.0.0/8". An
+// empty list trusts no proxy.
+func New(list string) (Resolver, error) {
+	r := Resolver{}
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(item)
+			if addrErr != nil {
+				return Resolver{}, fmt.Errorf("invalid trusted proxy %q", item)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		r.trusted = append(r.trusted, prefix.Masked())
+	}
+	return r, nil
+}
+
+func (r Resolver) isTrusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that sent req. When the peer is
+// a trusted proxy, X-Forwarded-For is read from the right, since proxies
+// append to it, and the first address that is not a trusted proxy is the
+// client. Addresses to the left of that were supplied by the client and are
+// not believed.
+func (r Resolver) ClientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !r.isTrusted(peer) {
+		return host
+	}
+
+	client := peer
+	forwarded := strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if hop == "" {
+			continue
+		}
+		addr, err := netip.ParseAddr(hop)
+		if err != nil {
+			// a malformed entry cannot be traced further back
+			break
+		}
+		client = addr
+		if !r.isTrusted(addr) {
+			break
+		}
+	}
+	return client.Unmap().String()
+}
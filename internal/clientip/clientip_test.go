@@ -0,0 +1,45 @@
+package clientip
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := New("127.0.0.1, 10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		resolver   Resolver
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{Resolver{}, "203.0.113.7:5000", nil, "203.0.113.7"},
+		{Resolver{}, "127.0.0.1:5000", []string{"203.0.113.7"}, "127.0.0.1"},
+		{proxies, "127.0.0.1:5000", nil, "127.0.0.1"},
+		{proxies, "127.0.0.1:5000", []string{"203.0.113.7"}, "203.0.113.7"},
+		{proxies, "127.0.0.1:5000", []string{"198.51.100.1, 203.0.113.7, 10.1.2.3"}, "203.0.113.7"},
+		{proxies, "127.0.0.1:5000", []string{"198.51.100.1", "203.0.113.7"}, "203.0.113.7"},
+		{proxies, "127.0.0.1:5000", []string{"10.1.2.3, garbage"}, "127.0.0.1"},
+		{proxies, "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{proxies, "[::ffff:127.0.0.1]:5000", []string{"203.0.113.7"}, "203.0.113.7"},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = c.remoteAddr
+		for _, value := range c.forwarded {
+			req.Header.Add("X-Forwarded-For", value)
+		}
+		if got := c.resolver.ClientIP(req); got != c.want {
+			t.Errorf("ClientIP(%s, X-Forwarded-For %q) = %q, want %q", c.remoteAddr, c.forwarded, got, c.want)
+		}
+	}
+}
+
+func TestNewInvalid(t *testing.T) {
+	if _, err := New("10.0.0.0/8, proxy.internal"); err == nil {
+		t.Error("New with a host name succeeded, want error")
+	}
+}
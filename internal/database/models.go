@@ -11,21 +11,231 @@ import (
 	"github.com/google/uuid"
 )
 
+type ApiToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+}
+
+type Appeal struct {
+	ID           uuid.UUID
+	SuspensionID uuid.UUID
+	UserID       uuid.UUID
+	Message      string
+	CreatedAt    time.Time
+	ResolvedAt   sql.NullTime
+	ActionID     uuid.NullUUID
+}
+
+type AuditEvent struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	Kind      string
+	UserID    uuid.NullUUID
+	IpAddress string
+	Detail    string
+}
+
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Body      string
 	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	DeletedAt sql.NullTime
+	LikeCount int32
+	RechirpOf uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+	EditedAt  sql.NullTime
+	HiddenAt  sql.NullTime
 }
 
-type RefreshToken struct {
-	Token     string
+type ChirpFlag struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Reason     string
+	CreatedAt  time.Time
+	ResolvedAt sql.NullTime
+	ActionID   uuid.NullUUID
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Body       string
+	CreatedAt  time.Time
+	ReplacedAt time.Time
+}
+
+type ChirpTag struct {
+	ChirpID   uuid.UUID
+	TagID     uuid.UUID
+	CreatedAt time.Time
+}
+
+type EmailVerification struct {
+	TokenHash string
+	UserID    uuid.UUID
+	Email     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Like struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type LoginAttempt struct {
+	Kind         string
+	Subject      string
+	Failures     int32
+	LastFailedAt time.Time
+	LockedUntil  sql.NullTime
+}
+
+type Mention struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+type ModerationAction struct {
+	ID          uuid.UUID
+	ModeratorID uuid.NullUUID
+	Action      string
+	ChirpID     uuid.NullUUID
+	UserID      uuid.NullUUID
+	Note        string
+	CreatedAt   time.Time
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type Notification struct {
+	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
 	UserID    uuid.UUID
+	ActorID   uuid.UUID
+	Kind      string
+	ChirpID   uuid.NullUUID
+	ReadAt    sql.NullTime
+}
+
+type OauthClient struct {
+	ID           uuid.UUID
+	OwnerID      uuid.UUID
+	Name         string
+	SecretHash   sql.NullString
+	RedirectUris []string
+	CreatedAt    time.Time
+}
+
+type OauthCode struct {
+	CodeHash      string
+	ClientID      uuid.UUID
+	UserID        uuid.UUID
+	RedirectUri   sql.NullString
+	Scopes        []string
+	CodeChallenge string
+	CreatedAt     time.Time
+	ExpiresAt     time.Time
+	UsedAt        sql.NullTime
+	FamilyID      uuid.NullUUID
+}
+
+type PasswordReset struct {
+	TokenHash string
+	UserID    uuid.UUID
+	CreatedAt time.Time
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	UsedAt    sql.NullTime
+}
+
+type ProfanityWord struct {
+	Word      string
+	CreatedAt time.Time
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
+	CreatedAt time.Time
+	UsedAt    sql.NullTime
+}
+
+type RefreshToken struct {
+	Token      string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	ExpiresAt  time.Time
+	RevokedAt  sql.NullTime
+	FamilyID   uuid.UUID
+	ReplacedBy sql.NullString
+	UserAgent  string
+	IpAddress  string
+	LastUsedAt time.Time
+	ClientID   uuid.NullUUID
+	Scopes     []string
+}
+
+type Report struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	ReporterID uuid.UUID
+	Reason     string
+	Note       string
+	CreatedAt  time.Time
+	ResolvedAt sql.NullTime
+	ActionID   uuid.NullUUID
+}
+
+type ShadowBannedUser struct {
+	UserID uuid.UUID
+}
+
+type Suspension struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	ModeratorID uuid.NullUUID
+	Reason      string
+	CreatedAt   time.Time
+	EndsAt      sql.NullTime
+	Kind        string
+	LiftedAt    sql.NullTime
+}
+
+type Tag struct {
+	ID        uuid.UUID
+	Name      string
+	CreatedAt time.Time
 }
 
 type User struct {
@@ -34,4 +244,18 @@ type User struct {
 	UpdatedAt      time.Time
 	Email          string
 	HashedPassword string
+	Handle         string
+	DisplayName    string
+	Bio            string
+	AvatarUrl      string
+	VerifiedAt     sql.NullTime
+	Role           string
+}
+
+type UserTotp struct {
+	UserID       uuid.UUID
+	Secret       string
+	CreatedAt    time.Time
+	ConfirmedAt  sql.NullTime
+	LastUsedStep int64
 }
@@ -7,32 +7,54 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const addRefreshToken = `-- name: AddRefreshToken :one
-INSERT INTO refresh_tokens(token,created_at,updated_at,user_id,expires_at,revoked_at)
+INSERT INTO refresh_tokens(token,created_at,updated_at,user_id,expires_at,revoked_at,family_id,user_agent,ip_address,last_used_at,client_id,scopes)
 VALUES(
     $1,
     NOW(),
     NOW(),
     $2,
     $3,
-    NULL
+    NULL,
+    $4,
+    $5,
+    $6,
+    NOW(),
+    $7,
+    $8
 )
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by, user_agent, ip_address, last_used_at, client_id, scopes
 `
 
 type AddRefreshTokenParams struct {
 	Token     string
 	UserID    uuid.UUID
 	ExpiresAt time.Time
+	FamilyID  uuid.UUID
+	UserAgent string
+	IpAddress string
+	ClientID  uuid.NullUUID
+	Scopes    []string
 }
 
 func (q *Queries) AddRefreshToken(ctx context.Context, arg AddRefreshTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, addRefreshToken, arg.Token, arg.UserID, arg.ExpiresAt)
+	row := q.db.QueryRowContext(ctx, addRefreshToken,
+		arg.Token,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.FamilyID,
+		arg.UserAgent,
+		arg.IpAddress,
+		arg.ClientID,
+		pq.Array(arg.Scopes),
+	)
 	var i RefreshToken
 	err := row.Scan(
 		&i.Token,
@@ -41,30 +63,51 @@ func (q *Queries) AddRefreshToken(ctx context.Context, arg AddRefreshTokenParams
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedBy,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
 	)
 	return i, err
 }
 
+const countUsersWithRole = `-- name: CountUsersWithRole :one
+SELECT COUNT(*)
+FROM users
+WHERE role = $1
+`
+
+func (q *Queries) CountUsersWithRole(ctx context.Context, role string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUsersWithRole, role)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email,hashed_password)
+INSERT INTO users (id, created_at, updated_at, email,hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
-    
+    $2,
+    $3
 )
-RETURNING id, created_at, updated_at, email, hashed_password
+RETURNING id, created_at, updated_at, email, hashed_password, handle, display_name, bio, avatar_url, verified_at, role
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         string
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword, arg.Handle)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -72,15 +115,24 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.VerifiedAt,
+		&i.Role,
 	)
 	return i, err
 }
 
 const deleteChirp = `-- name: DeleteChirp :exec
-DELETE FROM chirps
+UPDATE chirps
+SET body = '', updated_at = NOW(), deleted_at = NOW()
 WHERE id = $1
 `
 
+// Chirps are tombstoned rather than removed so that replies keep their place
+// in the thread.
 func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, deleteChirp, id)
 	return err
@@ -96,7 +148,7 @@ func (q *Queries) DeleteUser(ctx context.Context) error {
 }
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, like_count, rechirp_of, quote_of, edited_at, hidden_at
 FROM chirps
 WHERE id = $1
 `
@@ -110,18 +162,233 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.EditedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT parent.id AS ancestor_id, parent.in_reply_to AS next_id, 1 AS depth
+    FROM chirps parent
+    JOIN chirps child ON child.in_reply_to = parent.id
+    WHERE child.id = $1::uuid
+      AND (parent.user_id = $2 OR parent.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+      AND NOT EXISTS (
+          SELECT 1
+          FROM blocks
+          WHERE (blocks.blocker_id = parent.user_id AND blocks.blocked_id = $2)
+             OR (blocks.blocker_id = $2 AND blocks.blocked_id = parent.user_id)
+      )
+      AND NOT EXISTS (
+          SELECT 1
+          FROM mutes
+          WHERE mutes.muter_id = $2 AND mutes.muted_id = parent.user_id
+      )
+    UNION ALL
+    SELECT parent.id, parent.in_reply_to, ancestors.depth + 1
+    FROM chirps parent
+    JOIN ancestors ON ancestors.next_id = parent.id
+    WHERE (parent.user_id = $2 OR parent.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+      AND NOT EXISTS (
+          SELECT 1
+          FROM blocks
+          WHERE (blocks.blocker_id = parent.user_id AND blocks.blocked_id = $2)
+             OR (blocks.blocker_id = $2 AND blocks.blocked_id = parent.user_id)
+      )
+      AND NOT EXISTS (
+          SELECT 1
+          FROM mutes
+          WHERE mutes.muter_id = $2 AND mutes.muted_id = parent.user_id
+      )
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.like_count, chirps.rechirp_of, chirps.quote_of, chirps.edited_at, chirps.hidden_at, depth
+FROM ancestors
+JOIN chirps ON chirps.id = ancestor_id
+ORDER BY depth DESC
+`
+
+type GetChirpAncestorsParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+type GetChirpAncestorsRow struct {
+	Chirp Chirp
+	Depth int32
+}
+
+// The walk up the thread stops at the first chirp the viewer may not see or
+// has muted.
+func (q *Queries) GetChirpAncestors(ctx context.Context, arg GetChirpAncestorsParams) ([]GetChirpAncestorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, arg.ID, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpAncestorsRow
+	for rows.Next() {
+		var i GetChirpAncestorsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.InReplyTo,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.LikeCount,
+			&i.Chirp.RechirpOf,
+			&i.Chirp.QuoteOf,
+			&i.Chirp.EditedAt,
+			&i.Chirp.HiddenAt,
+			&i.Depth,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpDescendants = `-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants AS (
+    SELECT chirps.id AS descendant_id, 1 AS depth
+    FROM chirps
+    WHERE chirps.in_reply_to = $1::uuid
+      AND (chirps.user_id = $2 OR chirps.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+      AND NOT EXISTS (
+          SELECT 1
+          FROM blocks
+          WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2)
+             OR (blocks.blocker_id = $2 AND blocks.blocked_id = chirps.user_id)
+      )
+      AND NOT EXISTS (
+          SELECT 1
+          FROM mutes
+          WHERE mutes.muter_id = $2 AND mutes.muted_id = chirps.user_id
+      )
+    UNION ALL
+    SELECT chirps.id, descendants.depth + 1
+    FROM chirps
+    JOIN descendants ON chirps.in_reply_to = descendants.descendant_id
+    WHERE descendants.depth < $3::int
+      AND (chirps.user_id = $2 OR chirps.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+      AND NOT EXISTS (
+          SELECT 1
+          FROM blocks
+          WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2)
+             OR (blocks.blocker_id = $2 AND blocks.blocked_id = chirps.user_id)
+      )
+      AND NOT EXISTS (
+          SELECT 1
+          FROM mutes
+          WHERE mutes.muter_id = $2 AND mutes.muted_id = chirps.user_id
+      )
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.like_count, chirps.rechirp_of, chirps.quote_of, chirps.edited_at, chirps.hidden_at, depth
+FROM descendants
+JOIN chirps ON chirps.id = descendant_id
+ORDER BY depth ASC, chirps.created_at ASC, chirps.id ASC
+`
+
+type GetChirpDescendantsParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.NullUUID
+	MaxDepth int32
+}
+
+type GetChirpDescendantsRow struct {
+	Chirp Chirp
+	Depth int32
+}
+
+// Replies the viewer may not see or has muted are left out along with the
+// replies to them.
+func (q *Queries) GetChirpDescendants(ctx context.Context, arg GetChirpDescendantsParams) ([]GetChirpDescendantsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpDescendants, arg.ID, arg.ViewerID, arg.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpDescendantsRow
+	for rows.Next() {
+		var i GetChirpDescendantsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.InReplyTo,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.LikeCount,
+			&i.Chirp.RechirpOf,
+			&i.Chirp.QuoteOf,
+			&i.Chirp.EditedAt,
+			&i.Chirp.HiddenAt,
+			&i.Depth,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpForUpdate = `-- name: GetChirpForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, like_count, rechirp_of, quote_of, edited_at, hidden_at
+FROM chirps
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetChirpForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForUpdate, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.EditedAt,
+		&i.HiddenAt,
 	)
 	return i, err
 }
 
-const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, like_count, rechirp_of, quote_of, edited_at, hidden_at
 FROM chirps
-ORDER BY created_at ASC
+WHERE id = ANY($1::uuid[])
 `
 
-func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps)
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
 	if err != nil {
 		return nil, err
 	}
@@ -135,6 +402,13 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -149,8 +423,36 @@ func (q *Queries) GetChirps(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
+const getRefreshTokenForUpdate = `-- name: GetRefreshTokenForUpdate :one
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by, user_agent, ip_address, last_used_at, client_id, scopes
+FROM refresh_tokens
+WHERE token = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetRefreshTokenForUpdate(ctx context.Context, token string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshTokenForUpdate, token)
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedBy,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
+	)
+	return i, err
+}
+
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, email, hashed_password
+SELECT id, created_at, updated_at, email, hashed_password, handle, display_name, bio, avatar_url, verified_at, role
 FROM users
 WHERE email = $1
 `
@@ -164,6 +466,62 @@ func (q *Queries) GetUser(ctx context.Context, email string) (User, error) {
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.VerifiedAt,
+		&i.Role,
+	)
+	return i, err
+}
+
+const getUserByHandle = `-- name: GetUserByHandle :one
+SELECT id, created_at, updated_at, email, hashed_password, handle, display_name, bio, avatar_url, verified_at, role
+FROM users
+WHERE handle = $1
+`
+
+func (q *Queries) GetUserByHandle(ctx context.Context, handle string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.VerifiedAt,
+		&i.Role,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, handle, display_name, bio, avatar_url, verified_at, role
+FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.VerifiedAt,
+		&i.Role,
 	)
 	return i, err
 }
@@ -181,6 +539,261 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (uu
 	return user_id, err
 }
 
+const getUsersByHandles = `-- name: GetUsersByHandles :many
+SELECT id, handle
+FROM users
+WHERE handle = ANY($1::text[])
+`
+
+type GetUsersByHandlesRow struct {
+	ID     uuid.UUID
+	Handle string
+}
+
+func (q *Queries) GetUsersByHandles(ctx context.Context, handles []string) ([]GetUsersByHandlesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByHandles, pq.Array(handles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUsersByHandlesRow
+	for rows.Next() {
+		var i GetUsersByHandlesRow
+		if err := rows.Scan(&i.ID, &i.Handle); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVisibleChirpsByIDs = `-- name: GetVisibleChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, like_count, rechirp_of, quote_of, edited_at, hidden_at
+FROM chirps
+WHERE id = ANY($1::uuid[])
+  AND (chirps.user_id = $2 OR chirps.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+  AND NOT EXISTS (
+      SELECT 1
+      FROM blocks
+      WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $2)
+         OR (blocks.blocker_id = $2 AND blocks.blocked_id = chirps.user_id)
+  )
+  AND NOT EXISTS (
+      SELECT 1
+      FROM mutes
+      WHERE mutes.muter_id = $2 AND mutes.muted_id = chirps.user_id
+  )
+`
+
+type GetVisibleChirpsByIDsParams struct {
+	Ids      []uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+// GetChirpsByIDs without the chirps the viewer may not see or has muted.
+func (q *Queries) GetVisibleChirpsByIDs(ctx context.Context, arg GetVisibleChirpsByIDsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getVisibleChirpsByIDs, pq.Array(arg.Ids), arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsAsc = `-- name: ListChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, like_count, rechirp_of, quote_of, edited_at, hidden_at
+FROM chirps
+WHERE deleted_at IS NULL
+  AND hidden_at IS NULL
+  AND ($1::uuid IS NULL OR user_id = $1)
+  AND ($2::timestamp IS NULL
+       OR (created_at, id) > ($2::timestamp, $3::uuid))
+  AND (chirps.user_id = $4 OR chirps.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+  AND NOT EXISTS (
+      SELECT 1
+      FROM blocks
+      WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $4)
+         OR (blocks.blocker_id = $4 AND blocks.blocked_id = chirps.user_id)
+  )
+  AND ($1::uuid IS NOT NULL OR NOT EXISTS (
+      SELECT 1
+      FROM mutes
+      WHERE mutes.muter_id = $4 AND mutes.muted_id = chirps.user_id
+  ))
+ORDER BY created_at ASC, id ASC
+LIMIT $5
+`
+
+type ListChirpsAscParams struct {
+	AuthorID       uuid.NullUUID
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	ViewerID       uuid.NullUUID
+	Limit          int32
+}
+
+func (q *Queries) ListChirpsAsc(ctx context.Context, arg ListChirpsAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsAsc,
+		arg.AuthorID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.ViewerID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChirpsDesc = `-- name: ListChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, like_count, rechirp_of, quote_of, edited_at, hidden_at
+FROM chirps
+WHERE deleted_at IS NULL
+  AND hidden_at IS NULL
+  AND ($1::uuid IS NULL OR user_id = $1)
+  AND ($2::timestamp IS NULL
+       OR (created_at, id) < ($2::timestamp, $3::uuid))
+  AND (chirps.user_id = $4 OR chirps.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+  AND NOT EXISTS (
+      SELECT 1
+      FROM blocks
+      WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = $4)
+         OR (blocks.blocker_id = $4 AND blocks.blocked_id = chirps.user_id)
+  )
+  AND ($1::uuid IS NOT NULL OR NOT EXISTS (
+      SELECT 1
+      FROM mutes
+      WHERE mutes.muter_id = $4 AND mutes.muted_id = chirps.user_id
+  ))
+ORDER BY created_at DESC, id DESC
+LIMIT $5
+`
+
+type ListChirpsDescParams struct {
+	AuthorID       uuid.NullUUID
+	AfterCreatedAt sql.NullTime
+	AfterID        uuid.NullUUID
+	ViewerID       uuid.NullUUID
+	Limit          int32
+}
+
+func (q *Queries) ListChirpsDesc(ctx context.Context, arg ListChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, listChirpsDesc,
+		arg.AuthorID,
+		arg.AfterCreatedAt,
+		arg.AfterID,
+		arg.ViewerID,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.EditedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = COALESCE(revoked_at, NOW())
+WHERE family_id = $1
+`
+
+// Called when a rotated token is presented again: whoever holds the family's
+// live token may have stolen it, so the whole family is logged out.
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, familyID)
+	return err
+}
+
 const revokeUserRefreshToken = `-- name: RevokeUserRefreshToken :exec
 UPDATE refresh_tokens
 SET updated_at = NOW(), revoked_at = NOW()
@@ -192,25 +805,56 @@ func (q *Queries) RevokeUserRefreshToken(ctx context.Context, token string) erro
 	return err
 }
 
+const rotateRefreshToken = `-- name: RotateRefreshToken :execrows
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW(), replaced_by = $2
+WHERE token = $1 AND revoked_at IS NULL AND expires_at > NOW()
+`
+
+type RotateRefreshTokenParams struct {
+	Token      string
+	ReplacedBy sql.NullString
+}
+
+func (q *Queries) RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rotateRefreshToken, arg.Token, arg.ReplacedBy)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const saveChirp = `-- name: SaveChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, rechirp_of, quote_of)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, like_count, rechirp_of, quote_of, edited_at, hidden_at
 `
 
 type SaveChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	RechirpOf uuid.NullUUID
+	QuoteOf   uuid.NullUUID
 }
 
 func (q *Queries) SaveChirp(ctx context.Context, arg SaveChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, saveChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, saveChirp,
+		arg.Body,
+		arg.UserID,
+		arg.InReplyTo,
+		arg.RechirpOf,
+		arg.QuoteOf,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -218,25 +862,175 @@ func (q *Queries) SaveChirp(ctx context.Context, arg SaveChirpParams) (Chirp, er
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.EditedAt,
+		&i.HiddenAt,
 	)
 	return i, err
 }
 
-const updateEmailandPassword = `-- name: UpdateEmailandPassword :one
+const setUserRole = `-- name: SetUserRole :one
 UPDATE users
-SET email = $1, hashed_password = $2
-WHERE id = $3
-RETURNING id, created_at, updated_at, email, hashed_password
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, handle, display_name, bio, avatar_url, verified_at, role
 `
 
-type UpdateEmailandPasswordParams struct {
-	Email          string
-	HashedPassword string
+type SetUserRoleParams struct {
+	ID   uuid.UUID
+	Role string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.ID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.VerifiedAt,
+		&i.Role,
+	)
+	return i, err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2, updated_at = NOW(), edited_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, like_count, rechirp_of, quote_of, edited_at, hidden_at
+`
+
+type UpdateChirpBodyParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.EditedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const updatePassword = `-- name: UpdatePassword :exec
+UPDATE users
+SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdatePasswordParams struct {
 	ID             uuid.UUID
+	HashedPassword string
+}
+
+func (q *Queries) UpdatePassword(ctx context.Context, arg UpdatePasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updatePassword, arg.ID, arg.HashedPassword)
+	return err
+}
+
+const updatePasswordHash = `-- name: UpdatePasswordHash :exec
+UPDATE users
+SET hashed_password = $1
+WHERE id = $2 AND hashed_password = $3
+`
+
+type UpdatePasswordHashParams struct {
+	HashedPassword    string
+	ID                uuid.UUID
+	OldHashedPassword string
+}
+
+// Only replaces the hash the new one was computed from, so rehashing on
+// login cannot undo a password change made at the same time.
+func (q *Queries) UpdatePasswordHash(ctx context.Context, arg UpdatePasswordHashParams) error {
+	_, err := q.db.ExecContext(ctx, updatePasswordHash, arg.HashedPassword, arg.ID, arg.OldHashedPassword)
+	return err
+}
+
+const updateProfile = `-- name: UpdateProfile :one
+UPDATE users
+SET handle = COALESCE($1, handle),
+    display_name = COALESCE($2, display_name),
+    bio = COALESCE($3, bio),
+    avatar_url = COALESCE($4, avatar_url),
+    updated_at = NOW()
+WHERE id = $5
+RETURNING id, created_at, updated_at, email, hashed_password, handle, display_name, bio, avatar_url, verified_at, role
+`
+
+type UpdateProfileParams struct {
+	Handle      sql.NullString
+	DisplayName sql.NullString
+	Bio         sql.NullString
+	AvatarUrl   sql.NullString
+	ID          uuid.UUID
+}
+
+func (q *Queries) UpdateProfile(ctx context.Context, arg UpdateProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateProfile,
+		arg.Handle,
+		arg.DisplayName,
+		arg.Bio,
+		arg.AvatarUrl,
+		arg.ID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.VerifiedAt,
+		&i.Role,
+	)
+	return i, err
+}
+
+const verifyEmail = `-- name: VerifyEmail :one
+UPDATE users
+SET email = $2, verified_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, handle, display_name, bio, avatar_url, verified_at, role
+`
+
+type VerifyEmailParams struct {
+	ID    uuid.UUID
+	Email string
 }
 
-func (q *Queries) UpdateEmailandPassword(ctx context.Context, arg UpdateEmailandPasswordParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateEmailandPassword, arg.Email, arg.HashedPassword, arg.ID)
+// Marks the account verified, switching it to email if this verified a
+// change of address.
+func (q *Queries) VerifyEmail(ctx context.Context, arg VerifyEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyEmail, arg.ID, arg.Email)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -244,6 +1038,12 @@ func (q *Queries) UpdateEmailandPassword(ctx context.Context, arg UpdateEmailand
 		&i.UpdatedAt,
 		&i.Email,
 		&i.HashedPassword,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarUrl,
+		&i.VerifiedAt,
+		&i.Role,
 	)
 	return i, err
 }
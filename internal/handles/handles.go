@@ -0,0 +1,70 @@
+package handles
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+const (
+	MinLength = 3
+	MaxLength = 30
+)
+
+// Normalize returns the canonical, lower-case form a handle is stored as.
+func Normalize(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+}
+
+// Valid reports whether a normalized handle is 3 to 30 characters of a-z, 0-9
+// and underscores.
+func Valid(handle string) bool {
+	if len(handle) < MinLength || len(handle) > MaxLength {
+		return false
+	}
+	for _, r := range handle {
+		if r != '_' && !('a' <= r && r <= 'z') && !('0' <= r && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// Generate returns a random handle for accounts that did not pick one.
+func Generate() (string, error) {
+	key := make([]byte, 6)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", err
+	}
+	return "user_" + hex.EncodeToString(key), nil
+}
+
+// Mentions returns the distinct valid handles @mentioned in body, in the order
+// they first appear. An @ inside a word, as in an email address, is not a
+// mention.
+func Mentions(body string) []string {
+	found := []string{}
+	seen := map[string]bool{}
+	for i := 0; i < len(body); i++ {
+		if body[i] != '@' || (i > 0 && isHandleRune(rune(body[i-1]))) {
+			continue
+		}
+		end := i + 1
+		for end < len(body) && isHandleRune(rune(body[end])) {
+			end++
+		}
+		handle := Normalize(body[i+1 : end])
+		i = end - 1
+		if !Valid(handle) || seen[handle] {
+			continue
+		}
+		seen[handle] = true
+		found = append(found, handle)
+	}
+	return found
+}
+
+func isHandleRune(r rune) bool {
+	return r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9')
+}
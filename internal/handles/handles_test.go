@@ -0,0 +1,46 @@
+package handles
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValid(t *testing.T) {
+	for handle, want := range map[string]bool{
+		"allan":                           true,
+		"al":                              false,
+		"allan_t99":                       true,
+		"Allan":                           false,
+		"allan.t":                         false,
+		"a_very_long_handle_that_is_31_c": false,
+	} {
+		if got := Valid(handle); got != want {
+			t.Errorf("Valid(%q) = %v, want %v", handle, got, want)
+		}
+	}
+}
+
+func TestMentions(t *testing.T) {
+	cases := map[string][]string{
+		"hello world":                  {},
+		"@allan have you met @Boots?":  {"allan", "boots"},
+		"mail allan@example.com":       {},
+		"@allan @ALLAN @al":            {"allan"},
+		"(@walrider) and @walrider_2.": {"walrider", "walrider_2"},
+	}
+	for body, want := range cases {
+		if got := Mentions(body); !reflect.DeepEqual(got, want) {
+			t.Errorf("Mentions(%q) = %q, want %q", body, got, want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	handle, err := Generate()
+	if err != nil {
+		t.Fatalf("cannot generate handle: %v", err)
+	}
+	if !Valid(handle) {
+		t.Errorf("generated handle %q is not valid", handle)
+	}
+}
@@ -0,0 +1,34 @@
+package lockout
+
+import "time"
+
+// Policy decides how long to refuse further attempts after a run of
+// failures. The first FreeAttempts failures cost nothing, then each failure
+// doubles the delay starting from BaseDelay. Once LockoutAfter failures have
+// been seen, or the delay would exceed LockoutDuration, the subject is locked
+// out for LockoutDuration.
+type Policy struct {
+	FreeAttempts    int
+	BaseDelay       time.Duration
+	LockoutAfter    int
+	LockoutDuration time.Duration
+}
+
+// Delay returns how long to wait after the given number of consecutive
+// failures, and whether that wait is a lockout rather than a backoff.
+func (p Policy) Delay(failures int) (time.Duration, bool) {
+	if failures >= p.LockoutAfter {
+		return p.LockoutDuration, true
+	}
+	if failures < p.FreeAttempts {
+		return 0, false
+	}
+	delay := p.BaseDelay
+	for i := p.FreeAttempts; i < failures; i++ {
+		delay *= 2
+		if delay >= p.LockoutDuration {
+			return p.LockoutDuration, true
+		}
+	}
+	return delay, false
+}
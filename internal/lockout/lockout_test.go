@@ -0,0 +1,37 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	policy := Policy{FreeAttempts: 3, BaseDelay: time.Second, LockoutAfter: 10, LockoutDuration: 15 * time.Minute}
+	cases := []struct {
+		failures int
+		delay    time.Duration
+		locked   bool
+	}{
+		{0, 0, false},
+		{2, 0, false},
+		{3, time.Second, false},
+		{4, 2 * time.Second, false},
+		{9, 64 * time.Second, false},
+		{10, 15 * time.Minute, true},
+		{1000, 15 * time.Minute, true},
+	}
+	for _, c := range cases {
+		delay, locked := policy.Delay(c.failures)
+		if delay != c.delay || locked != c.locked {
+			t.Errorf("Delay(%d) = %v, %v, want %v, %v", c.failures, delay, locked, c.delay, c.locked)
+		}
+	}
+}
+
+func TestDelayCapsAtLockout(t *testing.T) {
+	policy := Policy{FreeAttempts: 1, BaseDelay: time.Minute, LockoutAfter: 100, LockoutDuration: 5 * time.Minute}
+	delay, locked := policy.Delay(4)
+	if delay != 5*time.Minute || !locked {
+		t.Errorf("Delay(4) = %v, %v, want the 5m lockout", delay, locked)
+	}
+}
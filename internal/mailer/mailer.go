@@ -0,0 +1,139 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers email.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Bytes formats msg as an RFC 5322 message from the given address. Header
+// values containing line breaks are refused so they cannot inject headers.
+func (msg Message) Bytes(from string, date time.Time) ([]byte, error) {
+	for _, value := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, errors.New("mail header contains a line break")
+		}
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return buf.Bytes(), nil
+}
+
+// SMTPMailer sends mail through an SMTP server, using STARTTLS when the
+// server offers it.
+type SMTPMailer struct {
+	// Addr is the server's host:port
+	Addr string
+	From string
+	// Auth may be nil for servers that do not require authentication
+	Auth smtp.Auth
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := msg.Bytes(m.From, time.Now())
+	if err != nil {
+		return err
+	}
+	sender, err := envelopeAddress(m.From)
+	if err != nil {
+		return err
+	}
+	// net/smtp cannot be cancelled, so the context is only checked up front
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return smtp.SendMail(m.Addr, m.Auth, sender, []string{msg.To}, data)
+}
+
+// envelopeAddress returns the bare address in from, which may include a
+// display name as in "Chirpy <noreply@chirpy.local>". The display name only
+// belongs in the From header; SMTP's MAIL FROM takes the address alone.
+func envelopeAddress(from string) (string, error) {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return "", fmt.Errorf("invalid sender %q: %w", from, err)
+	}
+	return addr.Address, nil
+}
+
+// FileMailer writes each message to its own .eml file in Dir instead of
+// sending it, for local development.
+type FileMailer struct {
+	Dir  string
+	From string
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	data, err := msg.Bytes(m.From, now)
+	if err != nil {
+		return err
+	}
+	suffix := make([]byte, 4)
+	_, err = rand.Read(suffix)
+	if err != nil {
+		return errors.New("unable to generate data")
+	}
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	return os.WriteFile(filepath.Join(m.Dir, name), data, 0o600)
+}
+
+// LogMailer logs that each message would have been sent, then drops it. The
+// body is left out of the log since it may hold secrets such as reset links.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("Dropping email %q to %s: no mailer configured", msg.Subject, msg.To)
+	return nil
+}
+
+// MemoryMailer keeps the messages it is given, for tests.
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (m *MemoryMailer) Send(ctx context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message{}, m.messages...)
+}
@@ -0,0 +1,88 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageBytes(t *testing.T) {
+	msg := Message{To: "allan@example.com", Subject: "Reset your password", Body: "line one\nline two"}
+	data, err := msg.Bytes("noreply@chirpy.test", time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "From: noreply@chirpy.test\r\n" +
+		"To: allan@example.com\r\n" +
+		"Subject: Reset your password\r\n" +
+		"Date: Thu, 02 Jan 2025 03:04:05 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"line one\r\nline two"
+	if string(data) != want {
+		t.Errorf("Bytes() = %q, want %q", data, want)
+	}
+}
+
+func TestMessageBytesRefusesHeaderInjection(t *testing.T) {
+	msg := Message{To: "allan@example.com\r\nBcc: everyone@example.com", Subject: "hi"}
+	_, err := msg.Bytes("noreply@chirpy.test", time.Now())
+	if err == nil {
+		t.Error("expected an error for a recipient containing a line break")
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer := &FileMailer{Dir: dir, From: "noreply@chirpy.test"}
+	err := mailer.Send(context.Background(), Message{To: "allan@example.com", Subject: "hi", Body: "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one .eml file, got %v, %v", files, err)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "To: allan@example.com\r\n") {
+		t.Errorf("unexpected message %q", data)
+	}
+}
+
+func TestMemoryMailer(t *testing.T) {
+	mailer := &MemoryMailer{}
+	msg := Message{To: "allan@example.com", Subject: "hi", Body: "hello"}
+	err := mailer.Send(context.Background(), msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mailer.Messages(); len(got) != 1 || got[0] != msg {
+		t.Errorf("Messages() = %v, want [%v]", got, msg)
+	}
+}
+
+func TestEnvelopeAddress(t *testing.T) {
+	tests := []struct {
+		from    string
+		want    string
+		wantErr bool
+	}{
+		{"noreply@chirpy.test", "noreply@chirpy.test", false},
+		{"Chirpy <noreply@chirpy.test>", "noreply@chirpy.test", false},
+		{`"Chirpy Team" <noreply@chirpy.test>`, "noreply@chirpy.test", false},
+		{"Chirpy", "", true},
+	}
+	for _, tt := range tests {
+		got, err := envelopeAddress(tt.from)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("envelopeAddress(%q) = %q, %v, want %q", tt.from, got, err, tt.want)
+		}
+	}
+}
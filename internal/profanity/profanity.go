@@ -0,0 +1,275 @@
+package profanity
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Mask replaces every profane word. It has a fixed length so that it does
+// not hint at which word was masked.
+const Mask = "****"
+
+var folder = cases.Fold()
+
+// leet maps the digits and symbols commonly substituted for letters back to
+// the letters, so "k3rfuff1e" is caught along with "kerfuffle".
+var leet = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'9': 'g',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+	'|': 'i',
+	'+': 't',
+}
+
+// Filter finds words from a word list in text, however they are cased,
+// accented, spelled with lookalike characters or stretched out. A Filter is
+// never modified once built, so it is safe for concurrent use.
+type Filter struct {
+	// words maps the key of each listed word to the word, folded
+	words map[string]string
+	// stretched maps squeezed keys to the listed words with that squeezed
+	// key, for looking up words written with letters repeated
+	stretched map[string][]string
+}
+
+// New returns a filter for words. Words are folded the same way as the text
+// they are looked for in, so the list can be written in any case.
+func New(words []string) *Filter {
+	f := &Filter{words: map[string]string{}, stretched: map[string][]string{}}
+	for _, word := range words {
+		folded := Fold(word)
+		if folded != "" {
+			f.words[key(folded)] = folded
+			squeezed := squeeze(key(folded))
+			f.stretched[squeezed] = append(f.stretched[squeezed], folded)
+		}
+	}
+	return f
+}
+
+// ReadWords reads a word list with one word per line. Blank lines and lines
+// starting with # are skipped.
+func ReadWords(r io.Reader) ([]string, error) {
+	words := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+// Fold reduces a word to the form words are compared in: compatibility
+// characters such as fullwidth letters are replaced by their plain
+// equivalents, accents and invisible formatting characters are dropped,
+// case is folded and leetspeak is read as letters.
+func Fold(word string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(word) {
+		if unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Cf, r) {
+			continue
+		}
+		if letter, ok := leet[r]; ok {
+			r = letter
+		}
+		b.WriteRune(r)
+	}
+	return folder.String(b.String())
+}
+
+// key is what folded words are looked up by. "1" and "|" are as often
+// written for "l" as for "i", so the two letters are not told apart.
+func key(folded string) string {
+	return strings.ReplaceAll(folded, "l", "i")
+}
+
+// squeeze collapses runs of the same letter, so "kerfuuuuffle" and
+// "kerfuffle" both become "kerfufle".
+func squeeze(word string) string {
+	var b strings.Builder
+	var last rune = -1
+	for _, r := range word {
+		if r != last {
+			b.WriteRune(r)
+		}
+		last = r
+	}
+	return b.String()
+}
+
+// match is a profane word found in a text, at text[start:end].
+type match struct {
+	start, end int
+	word       string
+}
+
+func (f *Filter) matches(text string) []match {
+	found := []match{}
+	for _, token := range tokens(text) {
+		if word, ok := f.lookup(text[token.start:token.end]); ok {
+			found = append(found, match{token.start, token.end, word})
+			continue
+		}
+		// leetspeak symbols at the ends of a word are more likely to be
+		// punctuation, as in "kerfuffle!" or "@sharbert"
+		start, end := trimSymbols(text, token.start, token.end)
+		if start == end || (start == token.start && end == token.end) {
+			continue
+		}
+		if word, ok := f.lookup(text[start:end]); ok {
+			found = append(found, match{start, end, word})
+		}
+	}
+	return found
+}
+
+// lookup returns the listed word that word is a spelling of.
+func (f *Filter) lookup(word string) (string, bool) {
+	k := key(Fold(word))
+	if listed, ok := f.words[k]; ok {
+		return listed, true
+	}
+	for _, listed := range f.stretched[squeeze(k)] {
+		if stretches(k, key(listed)) {
+			return listed, true
+		}
+	}
+	return "", false
+}
+
+// stretches reports whether k is listed with some of its letters repeated
+// more. Every run of a letter in k must be at least as long as in listed, so
+// "as" and "bob" are not taken for "ass" and "boob". k and listed must
+// squeeze to the same word.
+func stretches(k, listed string) bool {
+	a, b := runs(k), runs(listed)
+	for i := range a {
+		if a[i] < b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// runs returns the length of each run of the same letter in word.
+func runs(word string) []int {
+	lengths := []int{}
+	var last rune = -1
+	for _, r := range word {
+		if r == last {
+			lengths[len(lengths)-1]++
+		} else {
+			lengths = append(lengths, 1)
+		}
+		last = r
+	}
+	return lengths
+}
+
+// Find returns the distinct listed words that appear in text, folded.
+func (f *Filter) Find(text string) []string {
+	words := []string{}
+	seen := map[string]bool{}
+	for _, m := range f.matches(text) {
+		if !seen[m.word] {
+			seen[m.word] = true
+			words = append(words, m.word)
+		}
+	}
+	return words
+}
+
+// Mask replaces each profane word in text with Mask, leaving everything
+// around it as it was.
+func (f *Filter) Mask(text string) string {
+	found := f.matches(text)
+	if len(found) == 0 {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range found {
+		b.WriteString(text[last:m.start])
+		b.WriteString(Mask)
+		last = m.end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+type token struct {
+	start, end int
+}
+
+// tokens splits text into words: runs of letters, digits, combining marks,
+// invisible formatting characters and leetspeak symbols.
+func tokens(text string) []token {
+	found := []token{}
+	start := -1
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			found = append(found, token{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		found = append(found, token{start, len(text)})
+	}
+	return found
+}
+
+func isWordRune(r rune) bool {
+	_, isLeet := leet[r]
+	return isLeet || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || unicode.Is(unicode.Cf, r)
+}
+
+// trimSymbols narrows text[start:end] to exclude leetspeak symbols at either
+// end. Digits are kept, since they are rarely punctuation.
+func trimSymbols(text string, start, end int) (int, int) {
+	for start < end {
+		r, size := utf8.DecodeRuneInString(text[start:end])
+		if !isSymbol(r) {
+			break
+		}
+		start += size
+	}
+	for start < end {
+		r, size := utf8.DecodeLastRuneInString(text[start:end])
+		if !isSymbol(r) {
+			break
+		}
+		end -= size
+	}
+	return start, end
+}
+
+func isSymbol(r rune) bool {
+	_, isLeet := leet[r]
+	return isLeet && !unicode.IsDigit(r)
+}
@@ -0,0 +1,69 @@
+package profanity
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testFilter = New([]string{"kerfuffle", "sharbert", "Fornax"})
+
+func TestMask(t *testing.T) {
+	cases := map[string]string{
+		"I had something interesting for breakfast": "I had something interesting for breakfast",
+		"what a kerfuffle":                          "what a ****",
+		"Kerfuffle! Sharbert, fornax.":              "****! ****, ****.",
+		"KERFUFFLE":                                 "****",
+		"k3rfuff1e and $h@rb3rt":                    "**** and ****",
+		"kerfuuuuffle":                              "****",
+		"ｋｅｒｆｕｆｆｌｅ":                                 "****",
+		"shärbërt":                                  "****",
+		"ker​fuffle":                                "****",
+		"@sharbert said so":                         "@**** said so",
+		"kerfuffles and sharberts":                  "kerfuffles and sharberts",
+		"forn ax":                                   "forn ax",
+	}
+	for text, want := range cases {
+		if got := testFilter.Mask(text); got != want {
+			t.Errorf("Mask(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestMaskIgnoresSqueezedLookalikes(t *testing.T) {
+	filter := New([]string{"ass", "boob", "hell"})
+	cases := map[string]string{
+		"as soon as bob said": "as soon as bob said",
+		"hei there":           "hei there",
+		"heel and hel":        "heel and hel",
+		"asss and booob":      "**** and ****",
+		"heeeell":             "****",
+	}
+	for text, want := range cases {
+		if got := filter.Mask(text); got != want {
+			t.Errorf("Mask(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	got := testFilter.Find("Fornax? fornax! What a K3rfuffle")
+	want := []string{"fornax", "kerfuffle"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Find() = %v, want %v", got, want)
+	}
+	if got := testFilter.Find("all clean"); len(got) != 0 {
+		t.Errorf("Find(clean text) = %v, want none", got)
+	}
+}
+
+func TestReadWords(t *testing.T) {
+	words, err := ReadWords(strings.NewReader("# house rules\nkerfuffle\n\n  Sharbert  \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"kerfuffle", "Sharbert"}
+	if !reflect.DeepEqual(words, want) {
+		t.Errorf("ReadWords() = %v, want %v", words, want)
+	}
+}
@@ -0,0 +1,64 @@
+package search
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// ParseQuery turns a user search string into PostgreSQL to_tsquery syntax.
+//
+// Bare words must all match, "quoted words" must appear as a phrase and a
+// trailing * matches any word with that prefix. Everything except letters and
+// digits is treated as a separator, so the result never contains tsquery
+// operators the user typed themselves.
+func ParseQuery(q string) (string, error) {
+	clauses := []string{}
+	rest := q
+	for rest != "" {
+		start := strings.IndexByte(rest, '"')
+		if start == -1 {
+			clauses = append(clauses, words(rest)...)
+			break
+		}
+		clauses = append(clauses, words(rest[:start])...)
+		rest = rest[start+1:]
+
+		end := strings.IndexByte(rest, '"')
+		phrase := rest
+		if end == -1 {
+			rest = ""
+		} else {
+			phrase = rest[:end]
+			rest = rest[end+1:]
+		}
+		if phraseWords := words(phrase); len(phraseWords) > 0 {
+			clauses = append(clauses, "("+strings.Join(phraseWords, " <-> ")+")")
+		}
+	}
+
+	if len(clauses) == 0 {
+		return "", errors.New("search query is empty")
+	}
+	return strings.Join(clauses, " & "), nil
+}
+
+// words splits s into lexemes, keeping a :* prefix marker on words that end in
+// an asterisk.
+func words(s string) []string {
+	lexemes := []string{}
+	for _, field := range strings.Fields(s) {
+		prefix := strings.HasSuffix(field, "*")
+		parts := strings.FieldsFunc(field, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for i, part := range parts {
+			lexeme := strings.ToLower(part)
+			if prefix && i == len(parts)-1 {
+				lexeme += ":*"
+			}
+			lexemes = append(lexemes, lexeme)
+		}
+	}
+	return lexemes
+}
@@ -0,0 +1,34 @@
+package search
+
+import "testing"
+
+func TestParseQuery(t *testing.T) {
+	cases := map[string]string{
+		"breakfast":              "breakfast",
+		"Good  breakfast":        "good & breakfast",
+		`"good breakfast" today`: "(good <-> breakfast) & today",
+		"break*":                 "break:*",
+		`"open phrase`:           "(open <-> phrase)",
+		"drop & tables | !now":   "drop & tables & now",
+		"it's":                   "it & s",
+		`café "déjà vu"`:         "café & (déjà <-> vu)",
+	}
+	for input, want := range cases {
+		got, err := ParseQuery(input)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) returned error: %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseQuery(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestParseQueryEmpty(t *testing.T) {
+	for _, input := range []string{"", "   ", `""`, "&|!"} {
+		if _, err := ParseQuery(input); err == nil {
+			t.Errorf("ParseQuery(%q) should have failed", input)
+		}
+	}
+}
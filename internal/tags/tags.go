@@ -0,0 +1,70 @@
+package tags
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is the longest tag, in runes, that Extract will return.
+const MaxLength = 64
+
+var folder = cases.Fold()
+
+// Extract returns the distinct normalized hashtags in body, in the order they
+// first appear.
+//
+// A hashtag is a # that starts the body or follows a character that cannot be
+// part of a tag, followed by letters, digits, combining marks or underscores.
+// Tags made only of digits and underscores are ignored so "#1" is not a tag.
+func Extract(body string) []string {
+	found := []string{}
+	seen := map[string]bool{}
+
+	runes := []rune(body)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '#' || (i > 0 && isTagRune(runes[i-1])) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isTagRune(runes[end]) {
+			end++
+		}
+		tag := Normalize(string(runes[i+1 : end]))
+		i = end - 1
+		if !valid(tag) || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		found = append(found, tag)
+	}
+	return found
+}
+
+// Normalize folds a tag to the form it is stored and looked up by, so that
+// "#Café", "#CAFÉ" and "#café" are the same tag.
+func Normalize(tag string) string {
+	tag = strings.TrimPrefix(tag, "#")
+	return norm.NFKC.String(folder.String(norm.NFKC.String(tag)))
+}
+
+func valid(tag string) bool {
+	length := 0
+	hasLetter := false
+	for _, r := range tag {
+		if !isTagRune(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			hasLetter = true
+		}
+		length++
+	}
+	return hasLetter && length <= MaxLength
+}
+
+func isTagRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
@@ -0,0 +1,36 @@
+package tags
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	cases := map[string][]string{
+		"no tags here":                     {},
+		"#Go is fun":                       {"go"},
+		"I love #golang, #GoLang and #go!": {"golang", "go"},
+		"email me at someone#tag":          {},
+		"#1 fan of #chirpy_2025":           {"chirpy_2025"},
+		"(#café) #CAFÉ #café":             {"café"},
+		"#ＦＵＬＬＷＩＤＴＨ":                       {"fullwidth"},
+		"##double":                         {"double"},
+		"#straße and #STRASSE":             {"strasse"},
+	}
+	for body, want := range cases {
+		got := Extract(body)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Extract(%q) = %q, want %q", body, got, want)
+		}
+	}
+}
+
+func TestExtractLength(t *testing.T) {
+	long := "#"
+	for i := 0; i < MaxLength+1; i++ {
+		long += "a"
+	}
+	if got := Extract(long); len(got) != 0 {
+		t.Errorf("tag longer than %d runes should be ignored, got %q", MaxLength, got)
+	}
+}
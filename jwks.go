@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aklantan/chirpy/internal/auth"
+)
+
+// loadJWTKeys builds the key set tokens are signed and verified with.
+// signingFile is a PEM private key; verifyFiles is a comma separated list of
+// PEM keys that tokens are still accepted from, such as the key being rotated
+// out. Without a signing key a throwaway one is generated, which is fine for
+// development but logs everyone out on restart.
+func loadJWTKeys(signingFile, verifyFiles string) (*auth.KeySet, error) {
+	var signing *auth.SigningKey
+	var err error
+	if signingFile == "" {
+		log.Printf("JWT_SIGNING_KEY_FILE not set, signing tokens with a temporary key")
+		signing, err = auth.GenerateSigningKey()
+	} else {
+		signing, err = readKeyFile(signingFile)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	others := []*auth.SigningKey{}
+	for _, path := range strings.Split(verifyFiles, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		key, err := readKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		others = append(others, key)
+	}
+	return auth.NewKeySet(signing, others...)
+}
+
+func readKeyFile(path string) (*auth.SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := auth.ParseKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// getJWKS publishes the public keys tokens can be verified with, so other
+// services can check Chirpy tokens without sharing any secret.
+func (cfg *apiConfig) getJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, 200, cfg.jwtKeys.JWKS())
+}
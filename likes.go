@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// likeTarget resolves the {chirpID} path value to a chirp that userID can
+// like: one they can see.
+func (cfg *apiConfig) likeTarget(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (uuid.UUID, bool) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID format")
+		return uuid.Nil, false
+	}
+	chirp, err := cfg.db_query.GetChirp(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid || chirp.HiddenAt.Valid {
+		respondWithError(w, 404, "chirp not found")
+		return uuid.Nil, false
+	}
+	visible, err := cfg.chirpVisibleTo(r.Context(), chirp, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve chirp")
+		return uuid.Nil, false
+	}
+	if !visible {
+		respondWithError(w, 404, "chirp not found")
+		return uuid.Nil, false
+	}
+	return chirp.ID, true
+}
+
+// likeChirp records the like and bumps the chirp's like_count in one
+// transaction. The count only moves when the like row is actually inserted, so
+// repeated or concurrent likes from the same user are counted once.
+func (cfg *apiConfig) likeChirp(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorize(w, r, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+	chirpID, ok := cfg.likeTarget(w, r, jwtUser)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "cannot like chirp")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	inserted, err := qtx.LikeChirp(r.Context(), database.LikeChirpParams{UserID: jwtUser, ChirpID: chirpID})
+	if err != nil {
+		respondWithError(w, 500, "cannot like chirp")
+		return
+	}
+	if inserted > 0 {
+		_, err = qtx.IncrementLikeCount(r.Context(), chirpID)
+		if err != nil {
+			respondWithError(w, 500, "cannot like chirp")
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, 500, "cannot like chirp")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) unlikeChirp(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorize(w, r, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID format")
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "cannot unlike chirp")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	deleted, err := qtx.UnlikeChirp(r.Context(), database.UnlikeChirpParams{UserID: jwtUser, ChirpID: chirpID})
+	if err != nil {
+		respondWithError(w, 500, "cannot unlike chirp")
+		return
+	}
+	if deleted > 0 {
+		_, err = qtx.DecrementLikeCount(r.Context(), chirpID)
+		if err != nil {
+			respondWithError(w, 500, "cannot unlike chirp")
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, 500, "cannot unlike chirp")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+// getUserLikes lists the chirps a user has liked, most recently liked first,
+// leaving out those the viewer may not see or has muted.
+func (cfg *apiConfig) getUserLikes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := cfg.pathUser(w, r)
+	if !ok {
+		return
+	}
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	if err := page.checkOrder(true); err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	viewer := cfg.viewer(r)
+	afterCreatedAt, afterID := page.afterCursor()
+	rows, err := cfg.db_query.ListUserLikes(r.Context(), database.ListUserLikesParams{
+		UserID:         userID,
+		AfterCreatedAt: afterCreatedAt,
+		AfterID:        afterID,
+		ViewerID:       viewer,
+		Limit:          page.Limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve likes")
+		return
+	}
+	if len(rows) > int(page.Limit) {
+		rows = rows[:page.Limit]
+		last := rows[len(rows)-1]
+		setNextLink(w, r, chirpCursor{CreatedAt: last.LikedAt, ID: last.Chirp.ID, Desc: true})
+	}
+
+	responseChirps := []chirpResponse{}
+	for _, row := range rows {
+		responseChirps = append(responseChirps, cfg.chirpToResponse(row.Chirp))
+	}
+	err = cfg.hydrateChirps(r.Context(), viewer, responseChirps)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve chirp details")
+		return
+	}
+	respondWithJSON(w, 200, responseChirps)
+}
+
+// setLikedByMe fills in liked_by_me for an authenticated viewer with a single
+// lookup for the whole page. Anonymous viewers get no liked_by_me field.
+func (cfg *apiConfig) setLikedByMe(ctx context.Context, viewer uuid.NullUUID, chirps []chirpResponse) error {
+	if !viewer.Valid || len(chirps) == 0 {
+		return nil
+	}
+	chirpIDs := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		chirpIDs = append(chirpIDs, chirp.ID)
+	}
+	liked, err := cfg.db_query.ListLikedChirpIDs(ctx, database.ListLikedChirpIDsParams{UserID: viewer.UUID, ChirpIds: chirpIDs})
+	if err != nil {
+		return err
+	}
+	likedSet := map[uuid.UUID]bool{}
+	for _, chirpID := range liked {
+		likedSet[chirpID] = true
+	}
+	for i := range chirps {
+		likedByMe := likedSet[chirps[i].ID]
+		chirps[i].LikedByMe = &likedByMe
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/aklantan/chirpy/internal/lockout"
+	"github.com/google/uuid"
+)
+
+const (
+	loginAttemptAccount = "account"
+	loginAttemptIP      = "ip"
+	// loginAttemptTOTP counts wrong second factors per user
+	loginAttemptTOTP = "totp"
+	// loginAttemptChallenge counts wrong second factors per challenge token
+	loginAttemptChallenge = "challenge"
+)
+
+var (
+	// accountLoginPolicy slows down guessing one account's password from
+	// many addresses
+	accountLoginPolicy = lockout.Policy{
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		LockoutAfter:    10,
+		LockoutDuration: 15 * time.Minute,
+	}
+	// ipLoginPolicy slows down one address guessing many accounts' passwords.
+	// It is looser, since many users can share an address.
+	ipLoginPolicy = lockout.Policy{
+		FreeAttempts:    20,
+		BaseDelay:       time.Second,
+		LockoutAfter:    100,
+		LockoutDuration: time.Hour,
+	}
+	// totpLoginPolicy slows down guessing a user's second factor once their
+	// password is known, however many challenge tokens are used.
+	totpLoginPolicy = lockout.Policy{
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		LockoutAfter:    10,
+		LockoutDuration: 15 * time.Minute,
+	}
+	// challengeLoginPolicy spends a challenge token after a few wrong codes.
+	challengeLoginPolicy = lockout.Policy{
+		FreeAttempts:    5,
+		LockoutAfter:    5,
+		LockoutDuration: challengeTokenExpiry,
+	}
+)
+
+var (
+	errIncorrectLogin = errors.New("incorrect password or email")
+	errLoginThrottled = errors.New("too many failed logins, try again later")
+	errIncorrectCode  = errors.New("incorrect code")
+)
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// checkLogin returns the user whose email and password r presents. Unknown
+// emails and wrong passwords both give errIncorrectLogin after the same
+// work, so responses do not reveal which accounts exist. While the account
+// or the client's address is locked out, checkLogin returns
+// errLoginThrottled and how long to wait without checking the password.
+func (cfg *apiConfig) checkLogin(r *http.Request, email, password string) (database.User, time.Duration, error) {
+	account := normalizeEmail(email)
+	subjects := []loginSubject{
+		{loginAttemptAccount, account, accountLoginPolicy, uuid.NullUUID{}},
+		{loginAttemptIP, cfg.clientIP(r), ipLoginPolicy, uuid.NullUUID{}},
+	}
+
+	// the address is only checked, not locked: holding it while the password
+	// is hashed would make everyone behind a shared address log in one at a
+	// time
+	retryAfter, err := loginRetryAfter(r.Context(), cfg.db_query, subjects[1:])
+	if err != nil {
+		return database.User{}, 0, err
+	}
+	if retryAfter > 0 {
+		return database.User{}, retryAfter, errLoginThrottled
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		return database.User{}, 0, err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	// the account stays locked while the password is checked, so concurrent
+	// attempts cannot all pass the check before any of them is counted
+	retryAfter, err = lockLoginSubjects(r.Context(), qtx, subjects[:1])
+	if err != nil {
+		return database.User{}, 0, err
+	}
+	if retryAfter > 0 {
+		return database.User{}, retryAfter, errLoginThrottled
+	}
+
+	dbUser, err := qtx.GetUser(r.Context(), email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, 0, err
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		cfg.passwords.Verify(cfg.dummyPasswordHash, password)
+		return database.User{}, 0, cfg.failLogin(r, tx, qtx, subjects)
+	}
+	rehash, err := cfg.passwords.Verify(dbUser.HashedPassword, password)
+	if errors.Is(err, auth.ErrPasswordMismatch) {
+		subjects[0].userID = uuid.NullUUID{UUID: dbUser.ID, Valid: true}
+		return database.User{}, 0, cfg.failLogin(r, tx, qtx, subjects)
+	}
+	if err != nil {
+		return database.User{}, 0, err
+	}
+
+	err = qtx.ClearAccountLoginFailures(r.Context(), account)
+	if err != nil {
+		return database.User{}, 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return database.User{}, 0, err
+	}
+	if rehash {
+		cfg.rehashPassword(r, dbUser, password)
+	}
+	return dbUser, 0, nil
+}
+
+// failLogin records a failed login against subjects and commits tx, giving
+// errIncorrectLogin if that worked.
+func (cfg *apiConfig) failLogin(r *http.Request, tx *sql.Tx, q *database.Queries, subjects []loginSubject) error {
+	if err := cfg.recordLoginFailure(r, q, subjects); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return errIncorrectLogin
+}
+
+// rehashPassword replaces a user's outdated password hash with one made with
+// the current algorithm and parameters. This is only possible while the
+// password itself is at hand, so it is done as users log in; a failure just
+// leaves the old hash for next time.
+func (cfg *apiConfig) rehashPassword(r *http.Request, dbUser database.User, password string) {
+	hash, err := cfg.passwords.Hash(password)
+	if err == nil {
+		err = cfg.db_query.UpdatePasswordHash(r.Context(), database.UpdatePasswordHashParams{
+			HashedPassword:    hash,
+			ID:                dbUser.ID,
+			OldHashedPassword: dbUser.HashedPassword,
+		})
+	}
+	if err != nil {
+		log.Printf("Error rehashing password: %s", err)
+	}
+}
+
+// loginSubject is something failed logins are counted against: an account,
+// a client address, or a user's second factor.
+type loginSubject struct {
+	kind    string
+	subject string
+	policy  lockout.Policy
+	// userID is who lockouts of the subject are audited against, if known
+	userID uuid.NullUUID
+}
+
+// lockLoginSubjects locks the subjects for the rest of q's transaction and
+// returns how long to wait until any of them may be tried again. Subjects
+// must always be given in the same order so that concurrent attempts cannot
+// deadlock.
+func lockLoginSubjects(ctx context.Context, q *database.Queries, subjects []loginSubject) (time.Duration, error) {
+	for _, s := range subjects {
+		err := q.LockLoginSubject(ctx, database.LockLoginSubjectParams{Kind: s.kind, Subject: s.subject})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return loginRetryAfter(ctx, q, subjects)
+}
+
+// loginRetryAfter returns how long to wait until any of subjects may be
+// tried again, without locking them.
+func loginRetryAfter(ctx context.Context, q *database.Queries, subjects []loginSubject) (time.Duration, error) {
+	var retryAfter time.Duration
+	for _, s := range subjects {
+		seconds, err := q.GetLoginRetryAfter(ctx, database.GetLoginRetryAfterParams{Kind: s.kind, Subject: s.subject})
+		if err != nil {
+			return 0, err
+		}
+		retryAfter = max(retryAfter, time.Duration(seconds)*time.Second)
+	}
+	return retryAfter, nil
+}
+
+// recordLoginFailure counts a failure against each subject, delaying the
+// next attempt for it as its policy requires. Every lockout is audited.
+// Stale rows are pruned here too, so the table only holds subjects that
+// failed within the last day rather than every email and address tried.
+func (cfg *apiConfig) recordLoginFailure(r *http.Request, q *database.Queries, subjects []loginSubject) error {
+	if err := q.DeleteStaleLoginAttempts(r.Context()); err != nil {
+		return err
+	}
+	for _, s := range subjects {
+		failures, err := q.RecordLoginFailure(r.Context(), database.RecordLoginFailureParams{
+			Kind:    s.kind,
+			Subject: s.subject,
+		})
+		if err != nil {
+			return err
+		}
+		delay, locked := s.policy.Delay(int(failures))
+		if delay == 0 {
+			continue
+		}
+		err = q.LockLogin(r.Context(), database.LockLoginParams{
+			LockSeconds: int32(delay / time.Second),
+			Kind:        s.kind,
+			Subject:     s.subject,
+		})
+		if err != nil {
+			return err
+		}
+		if locked {
+			cfg.recordAuditEvent(r, auditLoginLockout, s.userID,
+				fmt.Sprintf("%s %s locked out for %s after %d failures", s.kind, s.subject, delay, failures))
+		}
+	}
+	return nil
+}
+
+// respondWithLoginError answers a login that checkLogin refused.
+func respondWithLoginError(w http.ResponseWriter, retryAfter time.Duration, err error) {
+	switch {
+	case errors.Is(err, errLoginThrottled):
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter/time.Second)))
+		respondWithError(w, 429, err.Error())
+	case errors.Is(err, errIncorrectLogin):
+		respondWithError(w, 401, err.Error())
+	default:
+		respondWithError(w, 500, "cannot retrieve user")
+	}
+}
+
+// respondWithSecondFactorError is respondWithLoginError for the errors of
+// verifySecondFactor.
+func respondWithSecondFactorError(w http.ResponseWriter, retryAfter time.Duration, err error) {
+	if errors.Is(err, errIncorrectCode) {
+		respondWithError(w, 401, err.Error())
+		return
+	}
+	if !errors.Is(err, errLoginThrottled) {
+		respondWithError(w, 500, "cannot check code")
+		return
+	}
+	respondWithLoginError(w, retryAfter, err)
+}
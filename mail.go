@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"time"
+
+	"github.com/aklantan/chirpy/internal/mailer"
+)
+
+const (
+	defaultMailFrom = "Chirpy <noreply@chirpy.local>"
+	mailSendTimeout = 30 * time.Second
+)
+
+// loadMailer picks how email is delivered. SMTP_ADDR (host:port) sends it
+// through an SMTP server, logging in with SMTP_USERNAME and SMTP_PASSWORD if
+// set; otherwise MAIL_DIR saves each message there as a file. With neither
+// set mail is logged and dropped, so nobody receives it. MAIL_FROM sets the
+// sender.
+func loadMailer() (mailer.Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = defaultMailFrom
+	}
+	if _, err := mail.ParseAddress(from); err != nil {
+		return nil, fmt.Errorf("invalid MAIL_FROM: %w", err)
+	}
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		var smtpAuth smtp.Auth
+		if username := os.Getenv("SMTP_USERNAME"); username != "" {
+			smtpAuth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+		}
+		return &mailer.SMTPMailer{Addr: addr, From: from, Auth: smtpAuth}, nil
+	}
+	if dir := os.Getenv("MAIL_DIR"); dir != "" {
+		err := os.MkdirAll(dir, 0o700)
+		if err != nil {
+			return nil, err
+		}
+		return &mailer.FileMailer{Dir: dir, From: from}, nil
+	}
+	log.Printf("Neither SMTP_ADDR nor MAIL_DIR set, email will not be delivered")
+	return mailer.LogMailer{}, nil
+}
+
+// sendMail delivers msg in the background. Requests do not wait for it, so
+// how long they take does not reveal whether an email was sent.
+func (cfg *apiConfig) sendMail(msg mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailSendTimeout)
+		defer cancel()
+		err := cfg.mailer.Send(ctx, msg)
+		if err != nil {
+			log.Printf("Error sending %q email: %s", msg.Subject, err)
+		}
+	}()
+}
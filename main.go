@@ -1,21 +1,26 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"strings"
+	"slices"
 	"sync/atomic"
 	"time"
 
 	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/clientip"
 	"github.com/aklantan/chirpy/internal/database"
+	"github.com/aklantan/chirpy/internal/handles"
+	"github.com/aklantan/chirpy/internal/mailer"
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type errorResponse struct {
@@ -31,20 +36,31 @@ type validResponse struct {
 */
 
 type chirpResponse struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID        uuid.UUID      `json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Body      string         `json:"body"`
+	UserID    uuid.UUID      `json:"user_id"`
+	InReplyTo *uuid.UUID     `json:"in_reply_to,omitempty"`
+	Deleted   bool           `json:"deleted,omitempty"`
+	Hidden    bool           `json:"hidden,omitempty"`
+	LikeCount int32          `json:"like_count"`
+	LikedByMe *bool          `json:"liked_by_me,omitempty"`
+	RechirpOf *uuid.UUID     `json:"rechirp_of,omitempty"`
+	QuoteOf   *uuid.UUID     `json:"quote_of,omitempty"`
+	Original  *chirpResponse `json:"original,omitempty"`
+	Edited    bool           `json:"edited"`
 }
 
 type User struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Email     string    `json:"email"`
-	Token     string    `json:"token"`
-	Refresh   string    `json:"refresh_token"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	Handle        string    `json:"handle"`
+	EmailVerified bool      `json:"email_verified"`
+	Token         string    `json:"token,omitempty"`
+	Refresh       string    `json:"refresh_token,omitempty"`
 }
 
 type ChirpRequest struct {
@@ -57,8 +73,22 @@ API state and methods
 */
 type apiConfig struct {
 	fileserverHits atomic.Int32
+	// platform is "dev" in development, which enables resetHits
+	platform       string
+	db             *sql.DB
 	db_query       *database.Queries
-	tokenSecret    string
+	jwtKeys        *auth.KeySet
+	passwords      *auth.PasswordHasher
+	passwordPolicy auth.PasswordPolicy
+	// dummyPasswordHash is checked against when a login names an unknown
+	// email, so that it takes as long to refuse as a wrong password does
+	dummyPasswordHash string
+	mailer            mailer.Mailer
+	trending          trendingCache
+	profanity         *profanityEngine
+	// proxies are the reverse proxies whose X-Forwarded-For headers are
+	// believed
+	proxies clientip.Resolver
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -69,6 +99,107 @@ func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
 	})
 }
 
+// principal is who a request's bearer token acts for. Requests made with a
+// personal API token or by an OAuth client are limited to the scopes the
+// user granted; sessions from a password login are not.
+type principal struct {
+	UserID   uuid.UUID
+	Scoped   bool
+	Scopes   []string
+	ClientID string
+	// Role is only set for sessions from a password login
+	Role string
+}
+
+func (p principal) can(scope string) bool {
+	return !p.Scoped || slices.Contains(p.Scopes, scope)
+}
+
+// principal resolves the request's bearer token, which is either an access
+// JWT or a personal API token.
+func (cfg *apiConfig) principal(r *http.Request) (principal, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return principal{}, err
+	}
+	if !auth.IsAPIToken(token) {
+		claims, err := auth.ParseJWT(token, cfg.jwtKeys)
+		if err != nil {
+			return principal{}, err
+		}
+		userID, err := claims.UserID()
+		if err != nil {
+			return principal{}, err
+		}
+		if claims.Delegated() {
+			return principal{UserID: userID, Scoped: true, Scopes: claims.Scopes(), ClientID: claims.ClientID}, nil
+		}
+		return principal{UserID: userID, Role: claims.Role}, nil
+	}
+
+	apiToken, err := cfg.db_query.GetAPITokenByHash(r.Context(), auth.HashToken(token))
+	if err != nil {
+		return principal{}, err
+	}
+	err = cfg.db_query.TouchAPIToken(r.Context(), apiToken.ID)
+	if err != nil {
+		log.Printf("Error recording API token use: %s", err)
+	}
+	return principal{UserID: apiToken.UserID, Scoped: true, Scopes: apiToken.Scopes}, nil
+}
+
+// authorize returns the user a request acts for if it may use scope. When it
+// may not, authorize has already responded with 401 or 403. Suspended users'
+// tokens are refused, however long they were issued for.
+func (cfg *apiConfig) authorize(w http.ResponseWriter, r *http.Request, scope string) (uuid.UUID, bool) {
+	p, err := cfg.principal(r)
+	if err != nil {
+		respondWithError(w, 401, "incorrect token or user")
+		return uuid.Nil, false
+	}
+	if !p.can(scope) {
+		respondWithError(w, 403, "token does not have the "+scope+" scope")
+		return uuid.Nil, false
+	}
+	if !cfg.checkNotSuspended(w, r, p.UserID) {
+		return uuid.Nil, false
+	}
+	return p.UserID, true
+}
+
+// authorizeSession is authorize for managing the account itself: passwords,
+// sessions, 2FA, API tokens and OAuth clients. Personal API tokens and OAuth
+// clients can never do that, whatever their scopes.
+func (cfg *apiConfig) authorizeSession(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	p, err := cfg.principal(r)
+	if err != nil {
+		respondWithError(w, 401, "incorrect token or user")
+		return uuid.Nil, false
+	}
+	if p.ClientID != "" {
+		respondWithError(w, 403, "OAuth clients cannot manage the account")
+		return uuid.Nil, false
+	}
+	if p.Scoped {
+		respondWithError(w, 403, "API tokens cannot manage the account")
+		return uuid.Nil, false
+	}
+	if !cfg.checkNotSuspended(w, r, p.UserID) {
+		return uuid.Nil, false
+	}
+	return p.UserID, true
+}
+
+// viewer is the authenticated caller on endpoints that also serve anonymous
+// requests. It is null when no valid token with read access was presented.
+func (cfg *apiConfig) viewer(r *http.Request) uuid.NullUUID {
+	p, err := cfg.principal(r)
+	if err != nil || !p.can(auth.ScopeChirpsRead) {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: p.UserID, Valid: true}
+}
+
 func (cfg *apiConfig) writeHits(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(200)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -84,31 +215,56 @@ func (cfg *apiConfig) writeHits(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// resetHits deletes every user, so it is only enabled in development.
 func (cfg *apiConfig) resetHits(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(200)
-	cfg.db_query.DeleteUser(r.Context())
+	if cfg.platform != "dev" {
+		respondWithError(w, 403, "reset is only allowed in the dev platform")
+		return
+	}
+	err := cfg.db_query.DeleteUser(r.Context())
+	if err != nil {
+		respondWithError(w, 500, "cannot reset")
+		return
+	}
 	cfg.fileserverHits.Swap(0)
+	w.WriteHeader(200)
+}
+
+// checkCanPost reports whether userID may publish text, by posting or by
+// editing a chirp: they need a verified email address and must not be
+// suspended. When they may not, it has already responded.
+func (cfg *apiConfig) checkCanPost(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	dbUser, err := cfg.db_query.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve user")
+		return false
+	}
+	if !dbUser.VerifiedAt.Valid {
+		respondWithError(w, 403, "verify your email address before posting")
+		return false
+	}
+	return cfg.checkNotSuspended(w, r, userID)
 }
 
 func (cfg *apiConfig) addChirp(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Body string `json:"body"`
+		Body      string     `json:"body"`
+		InReplyTo *uuid.UUID `json:"in_reply_to"`
+		RechirpOf *uuid.UUID `json:"rechirp_of"`
+		QuoteOf   *uuid.UUID `json:"quote_of"`
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, 401, "No auth token found")
+	userID, ok := cfg.authorize(w, r, auth.ScopeChirpsWrite)
+	if !ok {
 		return
 	}
-	userID, err := auth.ValidateJWT(token, cfg.tokenSecret)
-	if err != nil {
-		respondWithError(w, 401, "incorrect token or user")
+	if !cfg.checkCanPost(w, r, userID) {
 		return
 	}
 
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
-	err = decoder.Decode(&params)
+	err := decoder.Decode(&params)
 	if err != nil {
 		log.Printf("Error decoding parameters: %s", err)
 		w.WriteHeader(500)
@@ -117,22 +273,109 @@ func (cfg *apiConfig) addChirp(w http.ResponseWriter, r *http.Request) {
 
 	var dat []byte
 
+	inReplyTo := uuid.NullUUID{}
+	if params.InReplyTo != nil {
+		parent, err := cfg.db_query.GetChirp(r.Context(), *params.InReplyTo)
+		if err != nil || parent.HiddenAt.Valid {
+			respondWithError(w, 404, "Chirp being replied to not found")
+			return
+		}
+		if parent.DeletedAt.Valid {
+			respondWithError(w, 400, "Cannot reply to a deleted chirp")
+			return
+		}
+		blocked, err := cfg.isBlockedBy(r.Context(), parent.UserID, uuid.NullUUID{UUID: userID, Valid: true})
+		if err != nil {
+			respondWithError(w, 500, "cannot retrieve chirp")
+			return
+		}
+		if blocked {
+			respondWithError(w, 403, "Cannot reply to this chirp")
+			return
+		}
+		inReplyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+
+	if params.RechirpOf != nil && params.QuoteOf != nil {
+		respondWithError(w, 400, "A chirp cannot be both a rechirp and a quote")
+		return
+	}
+	rechirpOf, ok := cfg.sharedChirp(w, r, userID, params.RechirpOf)
+	if !ok {
+		return
+	}
+	quoteOf, ok := cfg.sharedChirp(w, r, userID, params.QuoteOf)
+	if !ok {
+		return
+	}
+	if rechirpOf.Valid && params.Body != "" {
+		respondWithError(w, 400, "A rechirp cannot have a body, use quote_of instead")
+		return
+	}
+	if quoteOf.Valid && params.Body == "" {
+		respondWithError(w, 400, "A quote needs a body")
+		return
+	}
+
 	if len(params.Body) <= 140 {
+		flagReason, err := cfg.profanity.screen(params.Body)
+		if err != nil {
+			respondWithError(w, 400, err.Error())
+			return
+		}
 
-		chirp, err := cfg.db_query.SaveChirp(r.Context(), database.SaveChirpParams{Body: params.Body, UserID: userID})
+		tx, err := cfg.db.BeginTx(r.Context(), nil)
+		if err != nil {
+			respondWithError(w, 500, err.Error())
+			return
+		}
+		defer tx.Rollback()
+		qtx := cfg.db_query.WithTx(tx)
+
+		chirp, err := qtx.SaveChirp(r.Context(), database.SaveChirpParams{
+			Body:      params.Body,
+			UserID:    userID,
+			InReplyTo: inReplyTo,
+			RechirpOf: rechirpOf,
+			QuoteOf:   quoteOf,
+		})
+		if isUniqueViolation(err) {
+			respondWithError(w, 409, "Chirp already rechirped")
+			return
+		}
 		if err != nil {
 			respondWithError(w, 500, err.Error())
 			return
 		}
-		respBody := chirpResponse{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      removeProfanity(chirp.Body),
-			UserID:    chirp.UserID,
+		err = saveChirpTags(r.Context(), qtx, chirp.ID, chirp.Body)
+		if err != nil {
+			respondWithError(w, 500, err.Error())
+			return
+		}
+		err = saveChirpMentions(r.Context(), qtx, chirp)
+		if err != nil {
+			respondWithError(w, 500, err.Error())
+			return
+		}
+		if flagReason != "" {
+			err = qtx.CreateChirpFlag(r.Context(), database.CreateChirpFlagParams{ChirpID: chirp.ID, Reason: flagReason})
+			if err != nil {
+				respondWithError(w, 500, err.Error())
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			respondWithError(w, 500, err.Error())
+			return
+		}
+		respBody := []chirpResponse{cfg.chirpToResponse(chirp)}
+		err = cfg.expandOriginals(r.Context(), uuid.NullUUID{UUID: userID, Valid: true}, respBody)
+		if err != nil {
+			respondWithError(w, 500, "cannot retrieve shared chirp")
+			return
 		}
 
-		respondWithJSON(w, 201, respBody)
+		respondWithJSON(w, 201, respBody[0])
 		return
 	} else {
 		respBody := errorResponse{
@@ -153,10 +396,101 @@ func (cfg *apiConfig) addChirp(w http.ResponseWriter, r *http.Request) {
 
 }
 
+const (
+	accessTokenExpiry  = time.Hour
+	refreshTokenExpiry = 60 * 24 * time.Hour
+)
+
+var (
+	errRefreshTokenInvalid = errors.New("refresh token is invalid, revoked or expired")
+	errRefreshTokenReused  = errors.New("refresh token has already been used")
+)
+
+// issueRefreshToken stores a new refresh token described by arg, recording
+// the device that r came from.
+func (cfg *apiConfig) issueRefreshToken(r *http.Request, q *database.Queries, arg database.AddRefreshTokenParams) (string, error) {
+	refresh, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	arg.Token = refresh
+	arg.UserAgent = requestUserAgent(r)
+	arg.IpAddress = cfg.clientIP(r)
+	_, err = q.AddRefreshToken(r.Context(), arg)
+	if err != nil {
+		return "", err
+	}
+	return refresh, nil
+}
+
+// rotateRefreshToken revokes a refresh token and issues its replacement in
+// the same family. Only tokens issued to clientID are accepted, so session
+// and OAuth refresh tokens cannot stand in for each other. The replacement
+// keeps the token's client and scopes.
+//
+// A token that has already been rotated should never be seen again, so
+// presenting one revokes its whole family and returns errRefreshTokenReused.
+func (cfg *apiConfig) rotateRefreshToken(r *http.Request, token string, clientID uuid.NullUUID) (database.RefreshToken, string, error) {
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		return database.RefreshToken{}, "", err
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	stored, err := qtx.GetRefreshTokenForUpdate(r.Context(), token)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && stored.ClientID != clientID) {
+		return database.RefreshToken{}, "", errRefreshTokenInvalid
+	}
+	if err != nil {
+		return database.RefreshToken{}, "", err
+	}
+	if stored.ReplacedBy.Valid {
+		err = qtx.RevokeRefreshTokenFamily(r.Context(), stored.FamilyID)
+		if err != nil {
+			return database.RefreshToken{}, "", err
+		}
+		if err := tx.Commit(); err != nil {
+			return database.RefreshToken{}, "", err
+		}
+		log.Printf("Refresh token reused, revoked family %s of user %s", stored.FamilyID, stored.UserID)
+		return database.RefreshToken{}, "", errRefreshTokenReused
+	}
+
+	// the replacement keeps the family's expiry, so rotating never extends a
+	// login past refreshTokenExpiry
+	refresh, err := cfg.issueRefreshToken(r, qtx, database.AddRefreshTokenParams{
+		UserID:    stored.UserID,
+		ExpiresAt: stored.ExpiresAt,
+		FamilyID:  stored.FamilyID,
+		ClientID:  stored.ClientID,
+		Scopes:    stored.Scopes,
+	})
+	if err != nil {
+		return database.RefreshToken{}, "", err
+	}
+	rotated, err := qtx.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{
+		Token:      stored.Token,
+		ReplacedBy: sql.NullString{String: refresh, Valid: true},
+	})
+	if err != nil {
+		return database.RefreshToken{}, "", err
+	}
+	if rotated == 0 {
+		// revoked or expired
+		return database.RefreshToken{}, "", errRefreshTokenInvalid
+	}
+	if err := tx.Commit(); err != nil {
+		return database.RefreshToken{}, "", err
+	}
+	return stored, refresh, nil
+}
+
 func (cfg *apiConfig) addUser(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
@@ -166,18 +500,68 @@ func (cfg *apiConfig) addUser(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(500)
 		return
 	}
-	params.Password, _ = auth.HashPassword(params.Password)
-	dbUser, err := cfg.db_query.CreateUser(r.Context(), database.CreateUserParams{Email: params.Email, HashedPassword: params.Password})
+	handle := handles.Normalize(params.Handle)
+	if handle == "" {
+		handle, err = handles.Generate()
+		if err != nil {
+			respondWithError(w, 500, "cannot generate handle")
+			return
+		}
+	} else if !handles.Valid(handle) {
+		respondWithError(w, 400, "handle must be 3 to 30 letters, digits or underscores")
+		return
+	}
+	if !validEmail(params.Email) {
+		respondWithError(w, 400, "invalid email address")
+		return
+	}
+	err = cfg.passwordPolicy.Check(params.Password)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	hashedPassword, err := cfg.passwords.Hash(params.Password)
+	if err != nil {
+		respondWithError(w, 500, "cannot hash password")
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "cannot create user")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	dbUser, err := qtx.CreateUser(r.Context(), database.CreateUserParams{Email: params.Email, HashedPassword: hashedPassword, Handle: handle})
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "email or handle already taken")
+		return
+	}
 	if err != nil {
 		log.Printf("Error creating user: %s", err)
 		w.WriteHeader(500)
 		return
 	}
+	verification, err := issueEmailVerification(r.Context(), qtx, dbUser, dbUser.Email)
+	if err != nil {
+		respondWithError(w, 500, "cannot create user")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, 500, "cannot create user")
+		return
+	}
+	cfg.sendMail(verification)
+
 	user := User{
-		ID:        dbUser.ID,
-		CreatedAt: dbUser.CreatedAt,
-		UpdatedAt: dbUser.UpdatedAt,
-		Email:     dbUser.Email,
+		ID:            dbUser.ID,
+		CreatedAt:     dbUser.CreatedAt,
+		UpdatedAt:     dbUser.UpdatedAt,
+		Email:         dbUser.Email,
+		Handle:        dbUser.Handle,
+		EmailVerified: dbUser.VerifiedAt.Valid,
 	}
 	respondWithJSON(w, 201, user)
 }
@@ -196,58 +580,123 @@ func (cfg *apiConfig) loginUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dbUser, err := cfg.db_query.GetUser(r.Context(), params.Email)
+	dbUser, retryAfter, err := cfg.checkLogin(r, params.Email, params.Password)
 	if err != nil {
-		respondWithError(w, 500, "cannot retrieve user")
+		respondWithLoginError(w, retryAfter, err)
 		return
 	}
-	err = auth.CheckPasswordHash(dbUser.HashedPassword, params.Password)
-	if err != nil {
-		respondWithError(w, 401, "incorrect password or email")
+	if !cfg.checkNotSuspended(w, r, dbUser.ID) {
 		return
 	}
 
-	jwtExpiry := 3600 * time.Second
-	refreshExpiry := 1440 * time.Hour
+	totp, err := cfg.db_query.GetUserTOTP(r.Context(), dbUser.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 500, "cannot retrieve user")
+		return
+	}
+	if err == nil && totp.ConfirmedAt.Valid {
+		cfg.respondWithLoginChallenge(w, dbUser.ID)
+		return
+	}
+	cfg.completeLogin(w, r, dbUser)
+}
 
-	jwt, err := auth.MakeJWT(dbUser.ID, cfg.tokenSecret, jwtExpiry)
+// completeLogin responds with a new access token and a refresh token that
+// starts a new session for dbUser.
+func (cfg *apiConfig) completeLogin(w http.ResponseWriter, r *http.Request, dbUser database.User) {
+	jwt, err := auth.MakeJWT(dbUser.ID, dbUser.Role, cfg.jwtKeys, accessTokenExpiry)
 	if err != nil {
 		respondWithError(w, 500, "cannot create JWT token")
 		return
 	}
-	refresh, err := auth.MakeRefreshToken()
+	// each login starts a new refresh token family
+	refresh, err := cfg.issueRefreshToken(r, cfg.db_query, database.AddRefreshTokenParams{
+		UserID:    dbUser.ID,
+		ExpiresAt: time.Now().Add(refreshTokenExpiry),
+		FamilyID:  uuid.New(),
+	})
 	if err != nil {
 		respondWithError(w, 500, "cannot create refresh token")
 		return
 	}
 
-	cfg.db_query.AddRefreshToken(r.Context(), database.AddRefreshTokenParams{Token: refresh, UserID: dbUser.ID, ExpiresAt: time.Now().Add(refreshExpiry)})
-
 	user := User{
-		ID:        dbUser.ID,
-		CreatedAt: dbUser.CreatedAt,
-		UpdatedAt: dbUser.UpdatedAt,
-		Email:     dbUser.Email,
-		Token:     jwt,
-		Refresh:   refresh,
+		ID:            dbUser.ID,
+		CreatedAt:     dbUser.CreatedAt,
+		UpdatedAt:     dbUser.UpdatedAt,
+		Email:         dbUser.Email,
+		Handle:        dbUser.Handle,
+		EmailVerified: dbUser.VerifiedAt.Valid,
+		Token:         jwt,
+		Refresh:       refresh,
 	}
 	respondWithJSON(w, 200, user)
 }
 
 func (cfg *apiConfig) getChirps(w http.ResponseWriter, r *http.Request) {
-	chirps, err := cfg.db_query.GetChirps(r.Context())
+	page, err := parsePageParams(r.URL.Query())
 	if err != nil {
-		respondWithError(w, 500, err.Error())
+		respondWithError(w, 400, err.Error())
+		return
 	}
-	responseChirps := []chirpResponse{}
-	for _, dbChirp := range chirps {
-		responseChirps = append(responseChirps, chirpResponse{
-			ID:        dbChirp.ID, // Adjust field names based on your DB struct
-			CreatedAt: dbChirp.CreatedAt,
-			UpdatedAt: dbChirp.UpdatedAt,
-			Body:      dbChirp.Body,
-			UserID:    dbChirp.UserID,
+
+	sortDesc := false
+	switch r.URL.Query().Get("sort") {
+	case "", "asc":
+	case "desc":
+		sortDesc = true
+	default:
+		respondWithError(w, 400, "sort must be asc or desc")
+		return
+	}
+	if err := page.checkOrder(sortDesc); err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	authorID, err := parseOptionalUUID(r.URL.Query().Get("author_id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid author ID format")
+		return
+	}
+
+	afterCreatedAt, afterID := page.afterCursor()
+	viewer := cfg.viewer(r)
+
+	// fetch one extra row to find out whether there is a next page
+	var chirps []database.Chirp
+	if sortDesc {
+		chirps, err = cfg.db_query.ListChirpsDesc(r.Context(), database.ListChirpsDescParams{
+			AuthorID:       authorID,
+			AfterCreatedAt: afterCreatedAt,
+			AfterID:        afterID,
+			ViewerID:       viewer,
+			Limit:          page.Limit + 1,
 		})
+	} else {
+		chirps, err = cfg.db_query.ListChirpsAsc(r.Context(), database.ListChirpsAscParams{
+			AuthorID:       authorID,
+			AfterCreatedAt: afterCreatedAt,
+			AfterID:        afterID,
+			ViewerID:       viewer,
+			Limit:          page.Limit + 1,
+		})
+	}
+	if err != nil {
+		respondWithError(w, 500, err.Error())
+		return
+	}
+	if len(chirps) > int(page.Limit) {
+		chirps = chirps[:page.Limit]
+		last := chirps[len(chirps)-1]
+		setNextLink(w, r, chirpCursor{CreatedAt: last.CreatedAt, ID: last.ID, Desc: sortDesc})
+	}
+
+	responseChirps := cfg.chirpsToResponse(chirps)
+	err = cfg.hydrateChirps(r.Context(), viewer, responseChirps)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve chirp details")
+		return
 	}
 	respondWithJSON(w, 200, responseChirps)
 }
@@ -262,47 +711,89 @@ func (cfg *apiConfig) getChirp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	dbChirp, err := cfg.db_query.GetChirp(r.Context(), uuidValue)
-	if err != nil {
+	if err != nil || dbChirp.DeletedAt.Valid || dbChirp.HiddenAt.Valid {
 		// Here you should check if the error is because the chirp wasn't found
 		// and return 404 in that case, otherwise return 500
 		respondWithError(w, 404, "Chirp not found")
 		return
 	}
-	responseChirp := chirpResponse{
-		ID:        dbChirp.ID, // Adjust field names based on your DB struct
-		CreatedAt: dbChirp.CreatedAt,
-		UpdatedAt: dbChirp.UpdatedAt,
-		Body:      dbChirp.Body,
-		UserID:    dbChirp.UserID,
+	viewer := cfg.viewer(r)
+	visible, err := cfg.chirpVisibleTo(r.Context(), dbChirp, viewer)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve chirp")
+		return
+	}
+	if !visible {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	responseChirps := []chirpResponse{cfg.chirpToResponse(dbChirp)}
+	err = cfg.hydrateChirps(r.Context(), viewer, responseChirps)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve chirp details")
+		return
 	}
+	respondWithJSON(w, 200, responseChirps[0])
+}
 
-	respondWithJSON(w, 200, responseChirp)
+// chirpVisibleTo reports whether viewer may see chirp: a shadow banned
+// user's chirps are only visible to them, and a user's chirps are hidden from
+// the users they have blocked.
+func (cfg *apiConfig) chirpVisibleTo(ctx context.Context, chirp database.Chirp, viewer uuid.NullUUID) (bool, error) {
+	if viewer.Valid && viewer.UUID == chirp.UserID {
+		return true, nil
+	}
+	_, banned, err := cfg.activeSuspension(ctx, chirp.UserID, shadowBanKind)
+	if err != nil || banned {
+		return false, err
+	}
+	blocked, err := cfg.isBlockedBy(ctx, chirp.UserID, viewer)
+	return !blocked, err
 }
 
+// refreshUser trades a refresh token for a new access token and a new refresh
+// token in the same family, revoking the one presented. A token that has
+// already been rotated should never be seen again, so presenting one revokes
+// its whole family.
 func (cfg *apiConfig) refreshUser(w http.ResponseWriter, r *http.Request) {
-	type parameters struct {
-		Token string `json:"token"`
+	type response struct {
+		Token   string `json:"token"`
+		Refresh string `json:"refresh_token"`
 	}
 	refreshToken, err := auth.GetBearerToken(r.Header)
 	if err != nil {
 		respondWithError(w, 401, "no token found")
 		return
 	}
-	userToBeRefreshed, err := cfg.db_query.GetUserFromRefreshToken(r.Context(), refreshToken)
-	if err != nil {
+
+	stored, refresh, err := cfg.rotateRefreshToken(r, refreshToken, uuid.NullUUID{})
+	if errors.Is(err, errRefreshTokenReused) {
+		respondWithError(w, 401, err.Error())
+		return
+	}
+	if errors.Is(err, errRefreshTokenInvalid) {
 		respondWithError(w, 401, "no user found for token")
 		return
 	}
-	jwt, err := auth.MakeJWT(userToBeRefreshed, cfg.tokenSecret, 3600*time.Second)
+	if err != nil {
+		respondWithError(w, 500, "cannot refresh token")
+		return
+	}
+	// the role is looked up again so that changes to it reach the new token
+	dbUser, err := cfg.db_query.GetUserByID(r.Context(), stored.UserID)
 	if err != nil {
 		respondWithError(w, 500, "cannot create new JWT")
 		return
 	}
-	user := parameters{
-		Token: jwt,
+	if !cfg.checkNotSuspended(w, r, dbUser.ID) {
+		return
 	}
-	respondWithJSON(w, 200, user)
-
+	jwt, err := auth.MakeJWT(dbUser.ID, dbUser.Role, cfg.jwtKeys, accessTokenExpiry)
+	if err != nil {
+		respondWithError(w, 500, "cannot create new JWT")
+		return
+	}
+	respondWithJSON(w, 200, response{Token: jwt, Refresh: refresh})
 }
 
 func (cfg *apiConfig) revokeUser(w http.ResponseWriter, r *http.Request) {
@@ -315,11 +806,20 @@ func (cfg *apiConfig) revokeUser(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, 204, nil)
 }
 
+// updateEmailandPassword changes the user's password at once. A new email
+// address only replaces the current one once it has been verified, and the
+// current address is told about the change in case it was not the owner who
+// asked for it.
 func (cfg *apiConfig) updateEmailandPassword(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
 	}
+	type response struct {
+		User
+		// PendingEmail is the new address waiting to be verified
+		PendingEmail string `json:"pending_email,omitempty"`
+	}
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
 	err := decoder.Decode(&params)
@@ -328,63 +828,130 @@ func (cfg *apiConfig) updateEmailandPassword(w http.ResponseWriter, r *http.Requ
 		w.WriteHeader(400)
 		return
 	}
-	accessToken, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, 401, "no token found")
+	jwtUser, ok := cfg.authorizeSession(w, r)
+	if !ok {
 		return
 	}
-	jwtUser, err := auth.ValidateJWT(accessToken, cfg.tokenSecret)
+	if !validEmail(params.Email) {
+		respondWithError(w, 400, "invalid email address")
+		return
+	}
+	err = cfg.passwordPolicy.Check(params.Password)
 	if err != nil {
-		respondWithError(w, 401, "no userfound")
+		respondWithError(w, 400, err.Error())
 		return
 	}
-	hashed_password, err := auth.HashPassword(params.Password)
+	hashed_password, err := cfg.passwords.Hash(params.Password)
 	if err != nil {
 		respondWithError(w, 500, "cannot hash password")
 		return
 	}
-	user, err := cfg.db_query.UpdateEmailandPassword(r.Context(), database.UpdateEmailandPasswordParams{Email: params.Email, HashedPassword: hashed_password, ID: jwtUser})
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "cannot update email or password")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	user, err := qtx.GetUserByID(r.Context(), jwtUser)
 	if err != nil {
 		respondWithError(w, 401, "cannot update email or password")
 		return
 	}
+	err = qtx.UpdatePassword(r.Context(), database.UpdatePasswordParams{ID: jwtUser, HashedPassword: hashed_password})
+	if err != nil {
+		respondWithError(w, 500, "cannot update email or password")
+		return
+	}
+	pendingEmail := ""
+	verification := mailer.Message{}
+	if params.Email != user.Email {
+		_, err = qtx.GetUser(r.Context(), params.Email)
+		if err == nil {
+			respondWithError(w, 409, "email already taken")
+			return
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			respondWithError(w, 500, "cannot update email or password")
+			return
+		}
+		recent, err := qtx.CountRecentEmailVerifications(r.Context(), jwtUser)
+		if err != nil {
+			respondWithError(w, 500, "cannot update email or password")
+			return
+		}
+		if recent >= maxVerificationEmailsPerHour {
+			respondWithError(w, 429, "too many verification emails, try again later")
+			return
+		}
+		verification, err = issueEmailVerification(r.Context(), qtx, user, params.Email)
+		if err != nil {
+			respondWithError(w, 500, "cannot update email or password")
+			return
+		}
+		pendingEmail = params.Email
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, 500, "cannot update email or password")
+		return
+	}
+	if pendingEmail != "" {
+		cfg.sendMail(verification)
+		cfg.sendMail(emailChangeNotice(user))
+	}
+
 	userResponse := User{
-		ID:        user.ID,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-		Email:     user.Email,
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		Handle:        user.Handle,
+		EmailVerified: user.VerifiedAt.Valid,
 	}
-	respondWithJSON(w, 200, userResponse)
+	respondWithJSON(w, 200, response{User: userResponse, PendingEmail: pendingEmail})
 
 }
 
 func (cfg *apiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
 	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
 	chirp, err := cfg.db_query.GetChirp(r.Context(), chirpID)
-	if err != nil {
+	if err != nil || chirp.DeletedAt.Valid {
 		respondWithError(w, 404, "chirp not found")
 		return
 	}
 
-	accessToken, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, 401, "no token found")
+	jwtUser, ok := cfg.authorize(w, r, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+	if chirp.UserID != jwtUser {
+		respondWithError(w, 403, "user not authorised")
 		return
 	}
-	jwtUser, err := auth.ValidateJWT(accessToken, cfg.tokenSecret)
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
 	if err != nil {
-		respondWithError(w, 401, "no userfound")
+		respondWithError(w, 500, "cannot delete tweet")
 		return
 	}
-	if chirp.UserID != jwtUser {
-		respondWithError(w, 403, "user not authorised")
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	err = qtx.DeleteChirp(r.Context(), chirp.ID)
+	if err != nil {
+		respondWithError(w, 500, "cannot delete tweet")
 		return
 	}
-	err = cfg.db_query.DeleteChirp(r.Context(), chirp.ID)
+	err = qtx.DeleteChirpTags(r.Context(), chirp.ID)
 	if err != nil {
 		respondWithError(w, 500, "cannot delete tweet")
 		return
 	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, 500, "cannot delete tweet")
+		return
+	}
 	respondWithJSON(w, 204, nil)
 	return
 
@@ -394,6 +961,52 @@ func (cfg *apiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
 Response handling
 */
 
+func (cfg *apiConfig) chirpToResponse(dbChirp database.Chirp) chirpResponse {
+	resp := chirpResponse{
+		ID:        dbChirp.ID,
+		CreatedAt: dbChirp.CreatedAt,
+		UpdatedAt: dbChirp.UpdatedAt,
+		Body:      cfg.profanity.mask(dbChirp.Body),
+		UserID:    dbChirp.UserID,
+		Deleted:   dbChirp.DeletedAt.Valid,
+		LikeCount: dbChirp.LikeCount,
+		Edited:    dbChirp.EditedAt.Valid,
+	}
+	// hidden chirps keep their body for moderators, so it is withheld here
+	if dbChirp.HiddenAt.Valid {
+		resp.Body = ""
+		resp.Hidden = true
+	}
+	if dbChirp.InReplyTo.Valid {
+		resp.InReplyTo = &dbChirp.InReplyTo.UUID
+	}
+	if dbChirp.RechirpOf.Valid {
+		resp.RechirpOf = &dbChirp.RechirpOf.UUID
+	}
+	if dbChirp.QuoteOf.Valid {
+		resp.QuoteOf = &dbChirp.QuoteOf.UUID
+	}
+	return resp
+}
+
+// hydrateChirps adds the per-viewer and cross-chirp details to a page of
+// chirps: liked_by_me and the embedded original of rechirps and quotes.
+func (cfg *apiConfig) hydrateChirps(ctx context.Context, viewer uuid.NullUUID, chirps []chirpResponse) error {
+	err := cfg.setLikedByMe(ctx, viewer, chirps)
+	if err != nil {
+		return err
+	}
+	return cfg.expandOriginals(ctx, viewer, chirps)
+}
+
+func (cfg *apiConfig) chirpsToResponse(chirps []database.Chirp) []chirpResponse {
+	responseChirps := []chirpResponse{}
+	for _, dbChirp := range chirps {
+		responseChirps = append(responseChirps, cfg.chirpToResponse(dbChirp))
+	}
+	return responseChirps
+}
+
 func respondWithError(w http.ResponseWriter, code int, msg string) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -423,32 +1036,60 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.Write(dat)
 }
 
-/*
-profanity Filters
-*/
-
-var profanity = []string{"kerfuffle", "sharbert", "fornax"}
-
-func removeProfanity(text string) string {
-	splitText := strings.Split(text, " ")
-	for i, word := range splitText {
-		for _, prof := range profanity {
-			if strings.ToLower(word) == prof {
-				splitText[i] = "****"
-			}
-		}
+// parseOptionalUUID parses an optional query parameter; an empty string is a
+// null UUID rather than an error.
+func parseOptionalUUID(s string) (uuid.NullUUID, error) {
+	if s == "" {
+		return uuid.NullUUID{}, nil
+	}
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.NullUUID{}, err
 	}
-	returnString := strings.Join(splitText, " ")
-	return returnString
+	return uuid.NullUUID{UUID: id, Valid: true}, nil
+}
 
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
 }
 
 func main() {
 	godotenv.Load()
 
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
 	const port = "8081"
 
-	secretString := os.Getenv("TOKEN_STRING")
+	jwtKeys, err := loadJWTKeys(os.Getenv("JWT_SIGNING_KEY_FILE"), os.Getenv("JWT_VERIFY_KEY_FILES"))
+	if err != nil {
+		log.Fatalf("Error loading JWT keys: %s", err)
+	}
+
+	passwords, err := loadPasswordHasher()
+	if err != nil {
+		log.Fatalf("Error configuring password hashing: %s", err)
+	}
+	passwordPolicy, err := loadPasswordPolicy()
+	if err != nil {
+		log.Fatalf("Error loading password policy: %s", err)
+	}
+	dummyPasswordHash, err := passwords.Hash("not a real password")
+	if err != nil {
+		log.Fatalf("Error hashing password: %s", err)
+	}
+
+	proxies, err := clientip.New(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatalf("Error configuring trusted proxies: %s", err)
+	}
+
+	mail, err := loadMailer()
+	if err != nil {
+		log.Fatalf("Error configuring mail: %s", err)
+	}
 
 	dbURL := os.Getenv("DB_URL")
 
@@ -459,9 +1100,27 @@ func main() {
 
 	dbQueries := database.New(db)
 
+	profanityFilter, err := newProfanityEngine(os.Getenv("PROFANITY_ACTION"), os.Getenv("PROFANITY_WORDS_FILE"))
+	if err != nil {
+		log.Fatalf("Error configuring profanity filter: %s", err)
+	}
+	_, err = profanityFilter.load(context.Background(), dbQueries)
+	if err != nil {
+		log.Fatalf("Error loading profanity words: %s", err)
+	}
+
 	apiCfg := &apiConfig{
-		db_query:    dbQueries,
-		tokenSecret: secretString,
+		db:       db,
+		db_query: dbQueries,
+		jwtKeys:  jwtKeys,
+		platform: os.Getenv("PLATFORM"),
+
+		passwords:         passwords,
+		passwordPolicy:    passwordPolicy,
+		dummyPasswordHash: dummyPasswordHash,
+		mailer:            mail,
+		profanity:         profanityFilter,
+		proxies:           proxies,
 	}
 
 	mux := http.NewServeMux()
@@ -472,18 +1131,82 @@ func main() {
 	}
 
 	mux.Handle("/app/", apiCfg.middlewareMetricsInc(http.FileServer(http.Dir("./"))))
-	mux.HandleFunc("POST /admin/reset", apiCfg.resetHits)
-	mux.HandleFunc("GET /admin/metrics", apiCfg.writeHits)
+
+	// every /admin/ route needs at least a moderator, and some an admin
+	admin := http.NewServeMux()
+	mux.Handle("/admin/", apiCfg.requireRole(auth.RoleModerator, admin))
+	adminOnly := func(handler http.HandlerFunc) http.Handler {
+		return apiCfg.requireRole(auth.RoleAdmin, handler)
+	}
+	admin.Handle("POST /admin/reset", adminOnly(apiCfg.resetHits))
+	admin.Handle("GET /admin/metrics", adminOnly(apiCfg.writeHits))
+	admin.Handle("POST /admin/profanity/reload", adminOnly(apiCfg.reloadProfanity))
+	admin.Handle("PUT /admin/users/{userID}/role", adminOnly(apiCfg.setUserRole))
+	admin.HandleFunc("GET /admin/moderation", apiCfg.getModerationQueue)
+	admin.HandleFunc("GET /admin/moderation/actions", apiCfg.getModerationActions)
+	admin.HandleFunc("POST /admin/moderation/chirps/{chirpID}/actions", apiCfg.moderateChirp)
+	admin.HandleFunc("GET /admin/moderation/appeals", apiCfg.getAppeals)
+	admin.HandleFunc("POST /admin/moderation/appeals/{appealID}", apiCfg.resolveAppeal)
+	admin.HandleFunc("POST /admin/users/{userID}/suspensions", apiCfg.createSuspension)
+	admin.HandleFunc("DELETE /admin/users/{userID}/suspensions", apiCfg.liftSuspensions)
+
 	mux.HandleFunc("POST /api/chirps", apiCfg.addChirp)
 	mux.HandleFunc("POST /api/users", apiCfg.addUser)
 	mux.HandleFunc("GET /api/chirps", apiCfg.getChirps)
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.getChirp)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", apiCfg.getChirpThread)
 	mux.HandleFunc("POST /api/login", apiCfg.loginUser)
+	mux.HandleFunc("POST /api/login/2fa", apiCfg.loginTwoFactor)
+	mux.HandleFunc("POST /api/2fa/enroll", apiCfg.enrollTwoFactor)
+	mux.HandleFunc("POST /api/2fa/confirm", apiCfg.confirmTwoFactor)
+	mux.HandleFunc("POST /api/2fa/disable", apiCfg.disableTwoFactor)
+	mux.HandleFunc("POST /api/email/verify", apiCfg.verifyEmail)
+	mux.HandleFunc("POST /api/email/verify/resend", apiCfg.resendEmailVerification)
+	mux.HandleFunc("POST /api/password/forgot", apiCfg.forgotPassword)
+	mux.HandleFunc("POST /api/password/reset", apiCfg.resetPassword)
 	mux.HandleFunc("POST /api/refresh", apiCfg.refreshUser)
 	mux.HandleFunc("POST /api/revoke", apiCfg.revokeUser)
+	mux.HandleFunc("GET /api/sessions", apiCfg.getSessions)
+	mux.HandleFunc("DELETE /api/sessions/{sessionID}", apiCfg.revokeSession)
+	mux.HandleFunc("POST /api/logout-all", apiCfg.logoutAll)
+	mux.HandleFunc("POST /api/tokens", apiCfg.createAPIToken)
+	mux.HandleFunc("GET /api/tokens", apiCfg.getAPITokens)
+	mux.HandleFunc("DELETE /api/tokens/{tokenID}", apiCfg.revokeAPIToken)
+	mux.HandleFunc("POST /api/oauth/clients", apiCfg.createOAuthClient)
+	mux.HandleFunc("GET /api/oauth/clients", apiCfg.getOAuthClients)
+	mux.HandleFunc("DELETE /api/oauth/clients/{clientID}", apiCfg.deleteOAuthClient)
+	mux.HandleFunc("GET /oauth/authorize", apiCfg.authorizeOAuthClient)
+	mux.HandleFunc("POST /oauth/authorize", apiCfg.approveOAuthClient)
+	mux.HandleFunc("POST /oauth/token", apiCfg.oauthToken)
+	mux.HandleFunc("POST /oauth/introspect", apiCfg.introspectOAuthToken)
+	mux.HandleFunc("POST /oauth/revoke", apiCfg.revokeOAuthToken)
 	mux.HandleFunc("PUT /api/users", apiCfg.updateEmailandPassword)
+	mux.HandleFunc("GET /api/users/{handle}", apiCfg.getProfile)
+	mux.HandleFunc("PATCH /api/users/me", apiCfg.updateProfile)
 	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.deleteChirp)
-
+	mux.HandleFunc("PUT /api/chirps/{chirpID}", apiCfg.editChirp)
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", apiCfg.getChirpRevisions)
+	mux.HandleFunc("POST /api/users/{userID}/follow", apiCfg.followUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/follow", apiCfg.unfollowUser)
+	mux.HandleFunc("POST /api/users/{userID}/block", apiCfg.blockUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/block", apiCfg.unblockUser)
+	mux.HandleFunc("POST /api/users/{userID}/mute", apiCfg.muteUser)
+	mux.HandleFunc("DELETE /api/users/{userID}/mute", apiCfg.unmuteUser)
+	mux.HandleFunc("GET /api/users/{userID}/followers", apiCfg.getFollowers)
+	mux.HandleFunc("GET /api/users/{userID}/following", apiCfg.getFollowing)
+	mux.HandleFunc("GET /api/timeline", apiCfg.getTimeline)
+	mux.HandleFunc("GET /api/search/chirps", apiCfg.searchChirps)
+	mux.HandleFunc("GET /api/tags/trending", apiCfg.getTrendingTags)
+	mux.HandleFunc("GET /api/tags/{tag}/chirps", apiCfg.getTagChirps)
+	mux.HandleFunc("GET /api/notifications", apiCfg.getNotifications)
+	mux.HandleFunc("POST /api/notifications/read", apiCfg.readNotifications)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/likes", apiCfg.likeChirp)
+	mux.HandleFunc("DELETE /api/chirps/{chirpID}/likes", apiCfg.unlikeChirp)
+	mux.HandleFunc("POST /api/chirps/{chirpID}/reports", apiCfg.reportChirp)
+	mux.HandleFunc("POST /api/appeals", apiCfg.submitAppeal)
+	mux.HandleFunc("GET /api/users/{userID}/likes", apiCfg.getUserLikes)
+
+	mux.HandleFunc("GET /.well-known/jwks.json", apiCfg.getJWKS)
 	mux.HandleFunc("GET /api/healthz", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		w.WriteHeader(200)
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Actions a moderator can take on a chirp in the moderation queue. Each
+// resolves every open report and flag on the chirp.
+const (
+	moderationDismiss = "dismiss"
+	moderationHide    = "hide"
+	moderationDelete  = "delete"
+	// moderationSuspend suspends the chirp's author
+	moderationSuspend = "suspend"
+	// moderationShadowBan shadow bans the chirp's author
+	moderationShadowBan = "shadow_ban"
+)
+
+const maxModerationNoteLength = 1000
+
+// moderatedChirp shows moderators a chirp as it was written, even if it has
+// been hidden or contains profanity.
+type moderatedChirp struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Body      string    `json:"body"`
+	UserID    uuid.UUID `json:"user_id"`
+	Deleted   bool      `json:"deleted"`
+	Hidden    bool      `json:"hidden"`
+}
+
+type chirpFlagResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type moderationQueueItem struct {
+	Chirp         moderatedChirp      `json:"chirp"`
+	FirstOpenedAt time.Time           `json:"first_opened_at"`
+	Reports       []reportResponse    `json:"reports"`
+	Flags         []chirpFlagResponse `json:"flags"`
+}
+
+// getModerationQueue lists the chirps with open reports or profanity flags,
+// those waiting longest first, each with everything filed against it.
+func (cfg *apiConfig) getModerationQueue(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	afterCreatedAt, afterID := page.afterCursor()
+	rows, err := cfg.db_query.ListModerationQueue(r.Context(), database.ListModerationQueueParams{
+		AfterCreatedAt: afterCreatedAt,
+		AfterID:        afterID,
+		Limit:          page.Limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve moderation queue")
+		return
+	}
+	if len(rows) > int(page.Limit) {
+		rows = rows[:page.Limit]
+		last := rows[len(rows)-1]
+		setNextLink(w, r, chirpCursor{CreatedAt: last.FirstOpenedAt, ID: last.ChirpID})
+	}
+
+	ids := []uuid.UUID{}
+	items := map[uuid.UUID]*moderationQueueItem{}
+	for _, row := range rows {
+		ids = append(ids, row.ChirpID)
+		items[row.ChirpID] = &moderationQueueItem{
+			FirstOpenedAt: row.FirstOpenedAt,
+			Reports:       []reportResponse{},
+			Flags:         []chirpFlagResponse{},
+		}
+	}
+	chirps, err := cfg.db_query.GetChirpsByIDs(r.Context(), ids)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve moderation queue")
+		return
+	}
+	for _, chirp := range chirps {
+		items[chirp.ID].Chirp = moderatedChirp{
+			ID:        chirp.ID,
+			CreatedAt: chirp.CreatedAt,
+			Body:      chirp.Body,
+			UserID:    chirp.UserID,
+			Deleted:   chirp.DeletedAt.Valid,
+			Hidden:    chirp.HiddenAt.Valid,
+		}
+	}
+	reports, err := cfg.db_query.ListOpenReports(r.Context(), ids)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve moderation queue")
+		return
+	}
+	for _, report := range reports {
+		items[report.ChirpID].Reports = append(items[report.ChirpID].Reports, reportToResponse(report))
+	}
+	flags, err := cfg.db_query.ListOpenChirpFlags(r.Context(), ids)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve moderation queue")
+		return
+	}
+	for _, flag := range flags {
+		items[flag.ChirpID].Flags = append(items[flag.ChirpID].Flags, chirpFlagResponse{
+			ID:        flag.ID,
+			Reason:    flag.Reason,
+			CreatedAt: flag.CreatedAt,
+		})
+	}
+
+	queue := []moderationQueueItem{}
+	for _, id := range ids {
+		queue = append(queue, *items[id])
+	}
+	respondWithJSON(w, 200, queue)
+}
+
+type moderationActionResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	ModeratorID *uuid.UUID `json:"moderator_id"`
+	Action      string     `json:"action"`
+	ChirpID     *uuid.UUID `json:"chirp_id"`
+	UserID      *uuid.UUID `json:"user_id"`
+	Note        string     `json:"note"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func moderationActionToResponse(action database.ModerationAction) moderationActionResponse {
+	resp := moderationActionResponse{
+		ID:        action.ID,
+		Action:    action.Action,
+		Note:      action.Note,
+		CreatedAt: action.CreatedAt,
+	}
+	if action.ModeratorID.Valid {
+		resp.ModeratorID = &action.ModeratorID.UUID
+	}
+	if action.ChirpID.Valid {
+		resp.ChirpID = &action.ChirpID.UUID
+	}
+	if action.UserID.Valid {
+		resp.UserID = &action.UserID.UUID
+	}
+	return resp
+}
+
+// moderateChirp takes an action on a chirp, records it and resolves the
+// chirp's open reports and flags, all in one transaction. Suspending or
+// shadow banning the author needs a note, which is kept as the reason, and
+// lasts for duration_seconds or, if that is omitted, for good.
+func (cfg *apiConfig) moderateChirp(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Action          string `json:"action"`
+		Note            string `json:"note"`
+		DurationSeconds *int32 `json:"duration_seconds"`
+	}
+
+	moderator, _ := principalFrom(r.Context())
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID format")
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		w.WriteHeader(400)
+		return
+	}
+	switch params.Action {
+	case moderationDismiss, moderationHide, moderationDelete, moderationSuspend, moderationShadowBan:
+	default:
+		respondWithError(w, 400, "unknown moderation action")
+		return
+	}
+	if utf8.RuneCountInString(params.Note) > maxModerationNoteLength {
+		respondWithError(w, 400, "note is too long")
+		return
+	}
+	duration := sql.NullInt32{}
+	if params.DurationSeconds != nil {
+		if suspensionKinds[params.Action] == "" || *params.DurationSeconds <= 0 {
+			respondWithError(w, 400, "duration_seconds must be positive and is only allowed when suspending")
+			return
+		}
+		duration = sql.NullInt32{Int32: *params.DurationSeconds, Valid: true}
+	}
+	if suspensionKinds[params.Action] != "" && params.Note == "" {
+		respondWithError(w, 400, "a note is required to suspend a user")
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "cannot moderate chirp")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	chirp, err := qtx.GetChirpForUpdate(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, 404, "chirp not found")
+		return
+	}
+	switch params.Action {
+	case moderationHide:
+		err = qtx.HideChirp(r.Context(), chirp.ID)
+	case moderationDelete:
+		err = qtx.DeleteChirp(r.Context(), chirp.ID)
+		if err == nil {
+			err = qtx.DeleteChirpTags(r.Context(), chirp.ID)
+		}
+	case moderationSuspend, moderationShadowBan:
+		_, err = suspendUser(r.Context(), qtx, database.CreateSuspensionParams{
+			UserID:          chirp.UserID,
+			ModeratorID:     uuid.NullUUID{UUID: moderator.UserID, Valid: true},
+			Kind:            suspensionKinds[params.Action],
+			Reason:          params.Note,
+			DurationSeconds: duration,
+		})
+	}
+	if errors.Is(err, errSuspendStaff) {
+		respondWithError(w, 403, err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "cannot moderate chirp")
+		return
+	}
+
+	action, err := qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+		ModeratorID: uuid.NullUUID{UUID: moderator.UserID, Valid: true},
+		Action:      params.Action,
+		ChirpID:     uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		UserID:      uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+		Note:        params.Note,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot moderate chirp")
+		return
+	}
+	actionID := uuid.NullUUID{UUID: action.ID, Valid: true}
+	err = qtx.ResolveReports(r.Context(), database.ResolveReportsParams{ChirpID: chirp.ID, ActionID: actionID})
+	if err != nil {
+		respondWithError(w, 500, "cannot moderate chirp")
+		return
+	}
+	err = qtx.ResolveChirpFlags(r.Context(), database.ResolveChirpFlagsParams{ChirpID: chirp.ID, ActionID: actionID})
+	if err != nil {
+		respondWithError(w, 500, "cannot moderate chirp")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, 500, "cannot moderate chirp")
+		return
+	}
+	respondWithJSON(w, 201, moderationActionToResponse(action))
+}
+
+// getModerationActions lists every moderation action, newest first.
+func (cfg *apiConfig) getModerationActions(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	if err := page.checkOrder(true); err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	afterCreatedAt, afterID := page.afterCursor()
+	actions, err := cfg.db_query.ListModerationActions(r.Context(), database.ListModerationActionsParams{
+		AfterCreatedAt: afterCreatedAt,
+		AfterID:        afterID,
+		Limit:          page.Limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve moderation actions")
+		return
+	}
+	if len(actions) > int(page.Limit) {
+		actions = actions[:page.Limit]
+		last := actions[len(actions)-1]
+		setNextLink(w, r, chirpCursor{CreatedAt: last.CreatedAt, ID: last.ID, Desc: true})
+	}
+
+	responseActions := []moderationActionResponse{}
+	for _, action := range actions {
+		responseActions = append(responseActions, moderationActionToResponse(action))
+	}
+	respondWithJSON(w, 200, responseActions)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/aklantan/chirpy/internal/handles"
+	"github.com/google/uuid"
+)
+
+const notificationMention = "mention"
+
+type notificationResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Kind      string     `json:"kind"`
+	ActorID   uuid.UUID  `json:"actor_id"`
+	ChirpID   *uuid.UUID `json:"chirp_id,omitempty"`
+	Read      bool       `json:"read"`
+}
+
+type notificationsResponse struct {
+	UnreadCount   int64                  `json:"unread_count"`
+	Notifications []notificationResponse `json:"notifications"`
+}
+
+// saveChirpMentions records the users @mentioned in a chirp and notifies each
+// one the first time they are mentioned in it, so editing a chirp does not
+// notify the same user twice. Handles that match no user are ignored, as are
+// users who have blocked the author. Users who have muted the author are not
+// notified, and nobody is notified of a shadow banned author's mentions,
+// which would give their hidden chirps away.
+func saveChirpMentions(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	_, err := q.GetActiveSuspension(ctx, database.GetActiveSuspensionParams{UserID: chirp.UserID, Kind: shadowBanKind})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	notify := errors.Is(err, sql.ErrNoRows)
+
+	mentioned := []uuid.UUID{}
+	found := handles.Mentions(chirp.Body)
+	if len(found) > 0 {
+		users, err := q.GetUsersByHandles(ctx, found)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if user.ID == chirp.UserID {
+				continue
+			}
+			blocked, err := q.IsBlocked(ctx, database.IsBlockedParams{BlockerID: user.ID, BlockedID: chirp.UserID})
+			if err != nil {
+				return err
+			}
+			if !blocked {
+				mentioned = append(mentioned, user.ID)
+			}
+		}
+	}
+
+	err = q.DeleteStaleMentions(ctx, database.DeleteStaleMentionsParams{ChirpID: chirp.ID, KeepUserIds: mentioned})
+	if err != nil {
+		return err
+	}
+	for _, userID := range mentioned {
+		_, err := q.AddMention(ctx, database.AddMentionParams{ChirpID: chirp.ID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		if !notify {
+			continue
+		}
+		muted, err := q.IsMuted(ctx, database.IsMutedParams{MuterID: userID, MutedID: chirp.UserID})
+		if err != nil {
+			return err
+		}
+		if muted {
+			continue
+		}
+		err = q.CreateNotification(ctx, database.CreateNotificationParams{
+			UserID:  userID,
+			ActorID: chirp.UserID,
+			Kind:    notificationMention,
+			ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) getNotifications(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorize(w, r, auth.ScopeNotificationsRead)
+	if !ok {
+		return
+	}
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	if err := page.checkOrder(true); err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	afterCreatedAt, afterID := page.afterCursor()
+	notifications, err := cfg.db_query.ListNotifications(r.Context(), database.ListNotificationsParams{
+		UserID:         jwtUser,
+		UnreadOnly:     r.URL.Query().Get("unread") == "true",
+		AfterCreatedAt: afterCreatedAt,
+		AfterID:        afterID,
+		Limit:          page.Limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve notifications")
+		return
+	}
+	unread, err := cfg.db_query.CountUnreadNotifications(r.Context(), jwtUser)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve notifications")
+		return
+	}
+	if len(notifications) > int(page.Limit) {
+		notifications = notifications[:page.Limit]
+		last := notifications[len(notifications)-1]
+		setNextLink(w, r, chirpCursor{CreatedAt: last.CreatedAt, ID: last.ID, Desc: true})
+	}
+
+	resp := notificationsResponse{UnreadCount: unread, Notifications: []notificationResponse{}}
+	for _, notification := range notifications {
+		item := notificationResponse{
+			ID:        notification.ID,
+			CreatedAt: notification.CreatedAt,
+			Kind:      notification.Kind,
+			ActorID:   notification.ActorID,
+			Read:      notification.ReadAt.Valid,
+		}
+		if notification.ChirpID.Valid {
+			item.ChirpID = &notification.ChirpID.UUID
+		}
+		resp.Notifications = append(resp.Notifications, item)
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+// readNotifications marks the listed notifications as read, or every unread
+// notification when no IDs are given.
+func (cfg *apiConfig) readNotifications(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		IDs []uuid.UUID `json:"ids"`
+	}
+	jwtUser, ok := cfg.authorize(w, r, auth.ScopeNotificationsWrite)
+	if !ok {
+		return
+	}
+
+	params := parameters{}
+	if r.ContentLength != 0 {
+		decoder := json.NewDecoder(r.Body)
+		err := decoder.Decode(&params)
+		if err != nil {
+			log.Printf("Error decoding parameters: %s", err)
+			w.WriteHeader(400)
+			return
+		}
+	}
+
+	var err error
+	if len(params.IDs) == 0 {
+		err = cfg.db_query.MarkAllNotificationsRead(r.Context(), jwtUser)
+	} else {
+		err = cfg.db_query.MarkNotificationsRead(r.Context(), database.MarkNotificationsReadParams{UserID: jwtUser, Ids: params.IDs})
+	}
+	if err != nil {
+		respondWithError(w, 500, "cannot mark notifications read")
+		return
+	}
+	unread, err := cfg.db_query.CountUnreadNotifications(r.Context(), jwtUser)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve notifications")
+		return
+	}
+	respondWithJSON(w, 200, struct {
+		UnreadCount int64 `json:"unread_count"`
+	}{UnreadCount: unread})
+}
@@ -0,0 +1,631 @@
+package main
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// scopeDescriptions explain on the consent page what each scope lets a client
+// do.
+var scopeDescriptions = map[string]string{
+	auth.ScopeChirpsRead:         "Read chirps and your timeline",
+	auth.ScopeChirpsWrite:        "Post, edit, like and delete chirps",
+	auth.ScopeFollowsWrite:       "Follow and unfollow accounts",
+	auth.ScopeProfileWrite:       "Update your profile",
+	auth.ScopeNotificationsRead:  "Read your notifications",
+	auth.ScopeNotificationsWrite: "Mark your notifications as read",
+}
+
+var consentTemplate = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html>
+  <head>
+    <title>Authorize {{.Client.Name}} - Chirpy</title>
+  </head>
+  <body>
+    <h1>Authorize {{.Client.Name}}</h1>
+    <p>{{.Client.Name}} would like to:</p>
+    <ul>
+      {{range .Scopes}}<li>{{.}}</li>
+      {{end}}
+    </ul>
+    {{if .Error}}<p><strong>{{.Error}}</strong></p>{{end}}
+    <form method="post" action="/oauth/authorize">
+      <input type="hidden" name="response_type" value="code">
+      <input type="hidden" name="client_id" value="{{.Client.ID}}">
+      <input type="hidden" name="redirect_uri" value="{{.RequestedRedirectURI}}">
+      <input type="hidden" name="scope" value="{{.Scope}}">
+      <input type="hidden" name="state" value="{{.State}}">
+      <input type="hidden" name="code_challenge" value="{{.CodeChallenge}}">
+      <input type="hidden" name="code_challenge_method" value="S256">
+      <p><label>Email <input type="email" name="email" autocomplete="username"></label></p>
+      <p><label>Password <input type="password" name="password" autocomplete="current-password"></label></p>
+      <p><label>Two-factor code, if enabled <input type="text" name="code" autocomplete="one-time-code"></label></p>
+      <button type="submit" name="action" value="approve">Allow</button>
+      <button type="submit" name="action" value="deny">Deny</button>
+    </form>
+  </body>
+</html>
+`))
+
+// authorizationRequest is a validated request for a user to authorize a
+// client (RFC 6749 section 4.1.1).
+type authorizationRequest struct {
+	Client      database.OauthClient
+	RedirectURI string
+	// RequestedRedirectURI is the redirect_uri the client sent, empty if it
+	// relied on having a single registered URI
+	RequestedRedirectURI string
+	Scopes               []string
+	State                string
+	CodeChallenge        string
+}
+
+// oauthError is an error response from the OAuth endpoints, as described in
+// RFC 6749 sections 4.1.2.1 and 5.2.
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// parseAuthorizationRequest validates an authorization request. The client
+// and redirect_uri are checked first: until they are known to be good the
+// user must not be redirected anywhere, so those failures are returned as
+// errors to show the user. Once they are, any other problem is returned as
+// an oauthError to send back to the client.
+func (cfg *apiConfig) parseAuthorizationRequest(r *http.Request, values url.Values) (authorizationRequest, *oauthError, error) {
+	req := authorizationRequest{}
+	clientID, err := uuid.Parse(values.Get("client_id"))
+	if err != nil {
+		return req, nil, errors.New("unknown client")
+	}
+	req.Client, err = cfg.db_query.GetOAuthClient(r.Context(), clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return req, nil, errors.New("unknown client")
+	}
+	if err != nil {
+		log.Printf("Error retrieving OAuth client: %s", err)
+		return req, nil, errors.New("cannot retrieve client")
+	}
+	req.RequestedRedirectURI = values.Get("redirect_uri")
+	req.RedirectURI = req.RequestedRedirectURI
+	if req.RedirectURI == "" && len(req.Client.RedirectUris) == 1 {
+		req.RedirectURI = req.Client.RedirectUris[0]
+	}
+	// redirect URIs are compared exactly, so a registered URI cannot be
+	// extended to send codes somewhere else
+	if !slices.Contains(req.Client.RedirectUris, req.RedirectURI) {
+		return req, nil, errors.New("redirect_uri is not registered for this client")
+	}
+
+	req.State = values.Get("state")
+	for _, key := range []string{"response_type", "client_id", "redirect_uri", "scope", "state", "code_challenge", "code_challenge_method"} {
+		if len(values[key]) > 1 {
+			return req, &oauthError{Code: "invalid_request", Description: key + " is repeated"}, nil
+		}
+	}
+	if values.Get("response_type") != "code" {
+		return req, &oauthError{Code: "unsupported_response_type"}, nil
+	}
+	// PKCE is required of every client, confidential or not
+	req.CodeChallenge = values.Get("code_challenge")
+	if values.Get("code_challenge_method") != "S256" || !auth.ValidPKCEChallenge(req.CodeChallenge) {
+		return req, &oauthError{Code: "invalid_request", Description: "an S256 code_challenge is required"}, nil
+	}
+	req.Scopes, err = parseScopes(values.Get("scope"))
+	if err != nil || len(req.Scopes) == 0 {
+		return req, &oauthError{Code: "invalid_scope", Description: "at least one known scope is required"}, nil
+	}
+	return req, nil, nil
+}
+
+// parseScopes parses a space separated scope parameter into a sorted list of
+// known scopes.
+func parseScopes(scope string) ([]string, error) {
+	scopes := strings.Fields(scope)
+	for _, s := range scopes {
+		if !auth.ValidScope(s) {
+			return nil, errors.New("unknown scope " + s)
+		}
+	}
+	slices.Sort(scopes)
+	return slices.Compact(scopes), nil
+}
+
+// redirectToClient sends the user back to the client with params added to
+// its redirect URI.
+func redirectToClient(w http.ResponseWriter, r *http.Request, req authorizationRequest, params url.Values) {
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	// the redirect URI was registered with a valid URL, so it parses
+	redirect, _ := url.Parse(req.RedirectURI)
+	query := redirect.Query()
+	for key, value := range params {
+		query[key] = value
+	}
+	redirect.RawQuery = query.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func redirectWithOAuthError(w http.ResponseWriter, r *http.Request, req authorizationRequest, oauthErr *oauthError) {
+	params := url.Values{"error": {oauthErr.Code}}
+	if oauthErr.Description != "" {
+		params.Set("error_description", oauthErr.Description)
+	}
+	redirectToClient(w, r, req, params)
+}
+
+// renderConsent shows the page where the user signs in and approves or
+// denies a client.
+func renderConsent(w http.ResponseWriter, code int, req authorizationRequest, message string) {
+	scopes := []string{}
+	for _, scope := range req.Scopes {
+		scopes = append(scopes, scopeDescriptions[scope])
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	// the page must not be framed, or another site could trick users into
+	// approving a client
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	w.WriteHeader(code)
+	err := consentTemplate.Execute(w, struct {
+		authorizationRequest
+		Scopes []string
+		Scope  string
+		Error  string
+	}{req, scopes, strings.Join(req.Scopes, " "), message})
+	if err != nil {
+		log.Printf("Error rendering consent page: %s", err)
+	}
+}
+
+func (cfg *apiConfig) authorizeOAuthClient(w http.ResponseWriter, r *http.Request) {
+	req, oauthErr, err := cfg.parseAuthorizationRequest(r, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	if oauthErr != nil {
+		redirectWithOAuthError(w, r, req, oauthErr)
+		return
+	}
+	renderConsent(w, 200, req, "")
+}
+
+// approveOAuthClient handles the consent form. The user signs in on the form
+// itself, which also means another site cannot submit it for them.
+func (cfg *apiConfig) approveOAuthClient(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "invalid form", 400)
+		return
+	}
+	req, oauthErr, err := cfg.parseAuthorizationRequest(r, r.PostForm)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	if oauthErr != nil {
+		redirectWithOAuthError(w, r, req, oauthErr)
+		return
+	}
+	if r.PostForm.Get("action") != "approve" {
+		redirectWithOAuthError(w, r, req, &oauthError{Code: "access_denied"})
+		return
+	}
+
+	dbUser, retryAfter, err := cfg.checkLogin(r, r.PostForm.Get("email"), r.PostForm.Get("password"))
+	if errors.Is(err, errLoginThrottled) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter/time.Second)))
+		renderConsent(w, 429, req, "Too many failed sign-ins. Try again later.")
+		return
+	}
+	if errors.Is(err, errIncorrectLogin) {
+		renderConsent(w, 401, req, "Incorrect email or password.")
+		return
+	}
+	if err != nil {
+		http.Error(w, "cannot authorize client", 500)
+		return
+	}
+	_, suspended, err := cfg.activeSuspension(r.Context(), dbUser.ID, suspensionKind)
+	if err != nil {
+		http.Error(w, "cannot authorize client", 500)
+		return
+	}
+	if suspended {
+		renderConsent(w, 403, req, "This account is suspended.")
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "cannot authorize client", 500)
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	totp, err := qtx.GetUserTOTPForUpdate(r.Context(), dbUser.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "cannot authorize client", 500)
+		return
+	}
+	if err == nil && totp.ConfirmedAt.Valid {
+		retryAfter, err := cfg.verifySecondFactor(r, tx, qtx, totp, r.PostForm.Get("code"))
+		if errors.Is(err, errLoginThrottled) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter/time.Second)))
+			renderConsent(w, 429, req, "Too many incorrect two-factor codes. Try again later.")
+			return
+		}
+		if errors.Is(err, errIncorrectCode) {
+			renderConsent(w, 401, req, "Incorrect two-factor code.")
+			return
+		}
+		if err != nil {
+			http.Error(w, "cannot authorize client", 500)
+			return
+		}
+	}
+
+	code, err := auth.MakeOAuthSecret()
+	if err != nil {
+		http.Error(w, "cannot authorize client", 500)
+		return
+	}
+	err = qtx.CreateOAuthCode(r.Context(), database.CreateOAuthCodeParams{
+		CodeHash:      auth.HashToken(code),
+		ClientID:      req.Client.ID,
+		UserID:        dbUser.ID,
+		RedirectUri:   sql.NullString{String: req.RequestedRedirectURI, Valid: req.RequestedRedirectURI != ""},
+		Scopes:        req.Scopes,
+		CodeChallenge: req.CodeChallenge,
+	})
+	if err != nil {
+		http.Error(w, "cannot authorize client", 500)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "cannot authorize client", 500)
+		return
+	}
+	redirectToClient(w, r, req, url.Values{"code": {code}})
+}
+
+// tokenResponse is a successful token endpoint response (RFC 6749 section
+// 5.1).
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// respondWithOAuthJSON writes a response from an endpoint that can hand out
+// tokens, which must never be cached.
+func respondWithOAuthJSON(w http.ResponseWriter, code int, payload interface{}) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	respondWithJSON(w, code, payload)
+}
+
+func respondWithOAuthError(w http.ResponseWriter, code int, errCode, description string) {
+	respondWithOAuthJSON(w, code, oauthError{Code: errCode, Description: description})
+}
+
+// oauthClient authenticates the client calling the token, introspection or
+// revocation endpoint, with HTTP Basic authentication or with client_id and
+// client_secret in the form. Public clients only identify themselves. When
+// it fails, oauthClient has already responded with invalid_client.
+func (cfg *apiConfig) oauthClient(w http.ResponseWriter, r *http.Request) (database.OauthClient, bool) {
+	clientID, secret, basic := r.BasicAuth()
+	if basic {
+		// RFC 6749 section 2.3.1 form-encodes both before they are joined
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	fail := func() (database.OauthClient, bool) {
+		if basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+		}
+		respondWithOAuthError(w, 401, "invalid_client", "client authentication failed")
+		return database.OauthClient{}, false
+	}
+	id, err := uuid.Parse(clientID)
+	if err != nil {
+		return fail()
+	}
+	client, err := cfg.db_query.GetOAuthClient(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fail()
+	}
+	if err != nil {
+		respondWithOAuthError(w, 500, "server_error", "")
+		return database.OauthClient{}, false
+	}
+	if !client.SecretHash.Valid {
+		if secret != "" {
+			return fail()
+		}
+		return client, true
+	}
+	if subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash.String)) != 1 {
+		return fail()
+	}
+	return client, true
+}
+
+func (cfg *apiConfig) oauthToken(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		respondWithOAuthError(w, 400, "invalid_request", "invalid form")
+		return
+	}
+	client, ok := cfg.oauthClient(w, r)
+	if !ok {
+		return
+	}
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		cfg.exchangeAuthorizationCode(w, r, client)
+	case "refresh_token":
+		cfg.refreshOAuthToken(w, r, client)
+	default:
+		respondWithOAuthError(w, 400, "unsupported_grant_type", "")
+	}
+}
+
+// exchangeAuthorizationCode redeems an authorization code for the client it
+// was issued to. Codes can only be used once: if one is presented again it
+// may have been stolen, so the tokens it was exchanged for are revoked.
+func (cfg *apiConfig) exchangeAuthorizationCode(w http.ResponseWriter, r *http.Request, client database.OauthClient) {
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithOAuthError(w, 500, "server_error", "")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	code, err := qtx.GetOAuthCodeForUpdate(r.Context(), auth.HashToken(r.PostForm.Get("code")))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithOAuthError(w, 400, "invalid_grant", "unknown authorization code")
+		return
+	}
+	if err != nil {
+		respondWithOAuthError(w, 500, "server_error", "")
+		return
+	}
+	if code.UsedAt.Valid {
+		if code.FamilyID.Valid {
+			err = qtx.RevokeRefreshTokenFamily(r.Context(), code.FamilyID.UUID)
+			if err != nil {
+				respondWithOAuthError(w, 500, "server_error", "")
+				return
+			}
+			if err := tx.Commit(); err != nil {
+				respondWithOAuthError(w, 500, "server_error", "")
+				return
+			}
+			log.Printf("Authorization code reused, revoked family %s of user %s", code.FamilyID.UUID, code.UserID)
+		}
+		respondWithOAuthError(w, 400, "invalid_grant", "authorization code has already been used")
+		return
+	}
+	// redirect_uri must be repeated only if the authorization request
+	// included it (RFC 6749 section 4.1.3)
+	if code.Expired || code.ClientID != client.ID || (code.RedirectUri.Valid && code.RedirectUri.String != r.PostForm.Get("redirect_uri")) {
+		respondWithOAuthError(w, 400, "invalid_grant", "invalid authorization code")
+		return
+	}
+	if !auth.VerifyPKCE(r.PostForm.Get("code_verifier"), code.CodeChallenge) {
+		respondWithOAuthError(w, 400, "invalid_grant", "code_verifier does not match the code_challenge")
+		return
+	}
+
+	familyID := uuid.New()
+	refresh, err := cfg.issueRefreshToken(r, qtx, database.AddRefreshTokenParams{
+		UserID:    code.UserID,
+		ExpiresAt: time.Now().Add(refreshTokenExpiry),
+		FamilyID:  familyID,
+		ClientID:  uuid.NullUUID{UUID: client.ID, Valid: true},
+		Scopes:    code.Scopes,
+	})
+	if err != nil {
+		respondWithOAuthError(w, 500, "server_error", "")
+		return
+	}
+	err = qtx.UseOAuthCode(r.Context(), database.UseOAuthCodeParams{
+		CodeHash: code.CodeHash,
+		FamilyID: uuid.NullUUID{UUID: familyID, Valid: true},
+	})
+	if err != nil {
+		respondWithOAuthError(w, 500, "server_error", "")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithOAuthError(w, 500, "server_error", "")
+		return
+	}
+	cfg.respondWithOAuthTokens(w, code.UserID, client, code.Scopes, refresh)
+}
+
+// refreshOAuthToken rotates a client's refresh token. The client may ask for
+// an access token with fewer scopes than it was granted, but the new refresh
+// token keeps all of them.
+func (cfg *apiConfig) refreshOAuthToken(w http.ResponseWriter, r *http.Request, client database.OauthClient) {
+	clientID := uuid.NullUUID{UUID: client.ID, Valid: true}
+	// a refresh token's scopes never change as it is rotated, so they can be
+	// checked before rotating it
+	current, err := cfg.db_query.GetRefreshToken(r.Context(), r.PostForm.Get("refresh_token"))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && current.ClientID != clientID) {
+		respondWithOAuthError(w, 400, "invalid_grant", "invalid refresh token")
+		return
+	}
+	if err != nil {
+		respondWithOAuthError(w, 500, "server_error", "")
+		return
+	}
+	scopes := current.Scopes
+	if r.PostForm.Has("scope") {
+		requested, err := parseScopes(r.PostForm.Get("scope"))
+		if err != nil || len(requested) == 0 {
+			respondWithOAuthError(w, 400, "invalid_scope", "at least one known scope is required")
+			return
+		}
+		for _, scope := range requested {
+			if !slices.Contains(current.Scopes, scope) {
+				respondWithOAuthError(w, 400, "invalid_scope", "scope "+scope+" was not granted")
+				return
+			}
+		}
+		scopes = requested
+	}
+
+	stored, refresh, err := cfg.rotateRefreshToken(r, current.Token, clientID)
+	if errors.Is(err, errRefreshTokenReused) || errors.Is(err, errRefreshTokenInvalid) {
+		respondWithOAuthError(w, 400, "invalid_grant", err.Error())
+		return
+	}
+	if err != nil {
+		respondWithOAuthError(w, 500, "server_error", "")
+		return
+	}
+	cfg.respondWithOAuthTokens(w, stored.UserID, client, scopes, refresh)
+}
+
+func (cfg *apiConfig) respondWithOAuthTokens(w http.ResponseWriter, userID uuid.UUID, client database.OauthClient, scopes []string, refresh string) {
+	jwt, err := auth.MakeDelegatedJWT(userID, client.ID.String(), scopes, cfg.jwtKeys, accessTokenExpiry)
+	if err != nil {
+		respondWithOAuthError(w, 500, "server_error", "")
+		return
+	}
+	respondWithOAuthJSON(w, 200, tokenResponse{
+		AccessToken:  jwt,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(accessTokenExpiry.Seconds()),
+		RefreshToken: refresh,
+		Scope:        strings.Join(scopes, " "),
+	})
+}
+
+// introspectionResponse describes a token to the client it was issued to
+// (RFC 7662 section 2.2).
+type introspectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+// introspectOAuthToken tells a client whether a token it holds is still
+// active. Tokens issued to anyone else are reported as inactive, so a client
+// cannot use it to learn about other clients' or users' tokens.
+func (cfg *apiConfig) introspectOAuthToken(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		respondWithOAuthError(w, 400, "invalid_request", "invalid form")
+		return
+	}
+	client, ok := cfg.oauthClient(w, r)
+	if !ok {
+		return
+	}
+	token := r.PostForm.Get("token")
+	if auth.IsAPIToken(token) {
+		respondWithOAuthJSON(w, 200, introspectionResponse{})
+		return
+	}
+
+	claims, err := auth.ParseJWT(token, cfg.jwtKeys)
+	if err == nil {
+		if claims.ClientID != client.ID.String() {
+			respondWithOAuthJSON(w, 200, introspectionResponse{})
+			return
+		}
+		respondWithOAuthJSON(w, 200, introspectionResponse{
+			Active:    true,
+			Scope:     claims.Scope,
+			ClientID:  claims.ClientID,
+			Subject:   claims.Subject,
+			TokenType: "access_token",
+			ExpiresAt: claims.ExpiresAt.Unix(),
+			IssuedAt:  claims.IssuedAt.Unix(),
+		})
+		return
+	}
+
+	refresh, err := cfg.db_query.GetRefreshToken(r.Context(), token)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		respondWithOAuthError(w, 500, "server_error", "")
+		return
+	}
+	// rotated tokens are revoked as they are replaced, so only the latest
+	// token in a family is active
+	if err != nil || !refresh.Active || refresh.ClientID != (uuid.NullUUID{UUID: client.ID, Valid: true}) {
+		respondWithOAuthJSON(w, 200, introspectionResponse{})
+		return
+	}
+	respondWithOAuthJSON(w, 200, introspectionResponse{
+		Active:    true,
+		Scope:     strings.Join(refresh.Scopes, " "),
+		ClientID:  client.ID.String(),
+		Subject:   refresh.UserID.String(),
+		TokenType: "refresh_token",
+	})
+}
+
+// revokeOAuthToken lets a client give up a refresh token, for example when
+// the user signs out of it (RFC 7009). The whole family is revoked. Access
+// tokens cannot be revoked and simply expire. As the RFC requires, the
+// response is the same whether or not the token was valid.
+func (cfg *apiConfig) revokeOAuthToken(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		respondWithOAuthError(w, 400, "invalid_request", "invalid form")
+		return
+	}
+	client, ok := cfg.oauthClient(w, r)
+	if !ok {
+		return
+	}
+	refresh, err := cfg.db_query.GetRefreshToken(r.Context(), r.PostForm.Get("token"))
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(200)
+		return
+	}
+	if err != nil {
+		respondWithOAuthError(w, 500, "server_error", "")
+		return
+	}
+	if refresh.ClientID == (uuid.NullUUID{UUID: client.ID, Valid: true}) {
+		err = cfg.db_query.RevokeRefreshTokenFamily(r.Context(), refresh.FamilyID)
+		if err != nil {
+			respondWithOAuthError(w, 500, "server_error", "")
+			return
+		}
+	}
+	w.WriteHeader(200)
+}
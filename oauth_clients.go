@@ -0,0 +1,167 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	maxOAuthClientNameLength = 100
+	maxRedirectURIs          = 10
+	maxRedirectURILength     = 2048
+)
+
+type oauthClientResponse struct {
+	ID           uuid.UUID `json:"id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+	// Secret is only ever included in the response that registers the client
+	Secret string `json:"client_secret,omitempty"`
+}
+
+func oauthClientToResponse(client database.OauthClient) oauthClientResponse {
+	return oauthClientResponse{
+		ID:           client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Confidential: client.SecretHash.Valid,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+// validRedirectURI reports whether users may be sent back to uri with an
+// authorization code. It must be an absolute https URL without a fragment;
+// plain http is only allowed on the loopback interface, for native apps.
+func validRedirectURI(uri string) bool {
+	if len(uri) > maxRedirectURILength {
+		return false
+	}
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Host == "" || parsed.User != nil || parsed.Fragment != "" {
+		return false
+	}
+	switch parsed.Scheme {
+	case "https":
+		return true
+	case "http":
+		host := parsed.Hostname()
+		ip := net.ParseIP(host)
+		return host == "localhost" || (ip != nil && ip.IsLoopback())
+	}
+	return false
+}
+
+// createOAuthClient registers a third-party app that users can authorize to
+// act for them. Confidential clients, which run on a server, are given a
+// secret that is shown once; public clients rely on PKCE alone.
+func (cfg *apiConfig) createOAuthClient(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Confidential bool     `json:"confidential"`
+	}
+	jwtUser, ok := cfg.authorizeSession(w, r)
+	if !ok {
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	if params.Name == "" || utf8.RuneCountInString(params.Name) > maxOAuthClientNameLength {
+		respondWithError(w, 400, "name must be 1 to 100 characters")
+		return
+	}
+	if len(params.RedirectURIs) == 0 || len(params.RedirectURIs) > maxRedirectURIs {
+		respondWithError(w, 400, "between 1 and 10 redirect_uris are required")
+		return
+	}
+	for _, uri := range params.RedirectURIs {
+		if !validRedirectURI(uri) {
+			respondWithError(w, 400, "invalid redirect uri "+uri)
+			return
+		}
+	}
+
+	secret := ""
+	secretHash := sql.NullString{}
+	if params.Confidential {
+		secret, err = auth.MakeOAuthSecret()
+		if err != nil {
+			respondWithError(w, 500, "cannot create client")
+			return
+		}
+		secretHash = sql.NullString{String: auth.HashToken(secret), Valid: true}
+	}
+	client, err := cfg.db_query.CreateOAuthClient(r.Context(), database.CreateOAuthClientParams{
+		OwnerID:      jwtUser,
+		Name:         params.Name,
+		SecretHash:   secretHash,
+		RedirectUris: params.RedirectURIs,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot create client")
+		return
+	}
+	resp := oauthClientToResponse(client)
+	resp.Secret = secret
+	respondWithJSON(w, 201, resp)
+}
+
+func (cfg *apiConfig) getOAuthClients(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorizeSession(w, r)
+	if !ok {
+		return
+	}
+	clients, err := cfg.db_query.ListOAuthClients(r.Context(), jwtUser)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve clients")
+		return
+	}
+	responseClients := []oauthClientResponse{}
+	for _, client := range clients {
+		responseClients = append(responseClients, oauthClientToResponse(client))
+	}
+	respondWithJSON(w, 200, responseClients)
+}
+
+// deleteOAuthClient removes a client along with every token and
+// authorization code issued to it.
+func (cfg *apiConfig) deleteOAuthClient(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorizeSession(w, r)
+	if !ok {
+		return
+	}
+	clientID, err := uuid.Parse(r.PathValue("clientID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid client ID format")
+		return
+	}
+	deleted, err := cfg.db_query.DeleteOAuthClient(r.Context(), database.DeleteOAuthClientParams{ID: clientID, OwnerID: jwtUser})
+	if err != nil {
+		respondWithError(w, 500, "cannot delete client")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "client not found")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+/*
+Keyset pagination
+
+Listings are ordered by (created_at, id) and a cursor records the last row a
+client has seen, so rows inserted while a client is scrolling never shift the
+next page. The cursor is opaque to clients.
+*/
+
+type chirpCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+	// Rank is only set on search results, which are ordered by relevance first.
+	Rank *float32 `json:"r,omitempty"`
+	// Desc records that the listing was sorted newest first, so a cursor is
+	// not reused with the other order, where it would pick out the wrong page.
+	Desc bool `json:"d,omitempty"`
+}
+
+type pageParams struct {
+	Limit  int32
+	Cursor *chirpCursor
+}
+
+func encodeCursor(c chirpCursor) string {
+	dat, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(dat)
+}
+
+func decodeCursor(s string) (chirpCursor, error) {
+	c := chirpCursor{}
+	dat, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errors.New("invalid cursor")
+	}
+	if err := json.Unmarshal(dat, &c); err != nil || c.ID == uuid.Nil {
+		return c, errors.New("invalid cursor")
+	}
+	return c, nil
+}
+
+func parsePageParams(query url.Values) (pageParams, error) {
+	params := pageParams{Limit: defaultPageLimit}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return params, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		params.Limit = int32(n)
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		c, err := decodeCursor(cursor)
+		if err != nil {
+			return params, err
+		}
+		params.Cursor = &c
+	}
+	return params, nil
+}
+
+// afterCursor returns the keyset arguments for the sqlc list queries; both are
+// null on the first page.
+func (p pageParams) afterCursor() (sql.NullTime, uuid.NullUUID) {
+	if p.Cursor == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: p.Cursor.CreatedAt, Valid: true}, uuid.NullUUID{UUID: p.Cursor.ID, Valid: true}
+}
+
+// checkOrder rejects a cursor from a listing sorted the other way, given
+// whether this one is sorted newest first.
+func (p pageParams) checkOrder(desc bool) error {
+	if p.Cursor != nil && p.Cursor.Desc != desc {
+		return errors.New("cursor does not match sort")
+	}
+	return nil
+}
+
+// setNextLink points the client at the page following cursor, keeping every
+// other query parameter of the current request.
+func setNextLink(w http.ResponseWriter, r *http.Request, cursor chirpCursor) {
+	query := r.URL.Query()
+	query.Set("cursor", encodeCursor(cursor))
+	next := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	rank := float32(0.5)
+	for _, c := range []chirpCursor{
+		{CreatedAt: time.Date(2025, 5, 18, 12, 0, 0, 0, time.UTC), ID: uuid.New()},
+		{CreatedAt: time.Date(2025, 5, 18, 12, 0, 0, 0, time.UTC), ID: uuid.New(), Desc: true},
+		{CreatedAt: time.Date(2025, 5, 18, 12, 0, 0, 0, time.UTC), ID: uuid.New(), Rank: &rank},
+	} {
+		got, err := decodeCursor(encodeCursor(c))
+		if err != nil {
+			t.Fatalf("decodeCursor(encodeCursor(%+v)) error: %v", c, err)
+		}
+		if !got.CreatedAt.Equal(c.CreatedAt) || got.ID != c.ID || got.Desc != c.Desc ||
+			(got.Rank == nil) != (c.Rank == nil) || (c.Rank != nil && *got.Rank != *c.Rank) {
+			t.Errorf("decodeCursor(encodeCursor(%+v)) = %+v", c, got)
+		}
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	for _, s := range []string{
+		"not base64!",
+		"bm90IGpzb24",    // "not json"
+		"eyJ0IjpudWxsfQ", // {"t":null}, no ID
+	} {
+		if _, err := decodeCursor(s); err == nil {
+			t.Errorf("decodeCursor(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestParsePageParams(t *testing.T) {
+	cursor := encodeCursor(chirpCursor{CreatedAt: time.Now(), ID: uuid.New(), Desc: true})
+	cases := []struct {
+		query      string
+		wantLimit  int32
+		wantCursor bool
+		wantErr    bool
+	}{
+		{query: "", wantLimit: defaultPageLimit},
+		{query: "limit=5", wantLimit: 5},
+		{query: "limit=100", wantLimit: 100},
+		{query: "limit=0", wantErr: true},
+		{query: "limit=101", wantErr: true},
+		{query: "limit=ten", wantErr: true},
+		{query: "cursor=" + cursor, wantLimit: defaultPageLimit, wantCursor: true},
+		{query: "cursor=garbage", wantErr: true},
+	}
+	for _, tc := range cases {
+		query, _ := url.ParseQuery(tc.query)
+		page, err := parsePageParams(query)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parsePageParams(%q) error = %v, want error %v", tc.query, err, tc.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if page.Limit != tc.wantLimit || (page.Cursor != nil) != tc.wantCursor {
+			t.Errorf("parsePageParams(%q) = %+v, want limit %d, cursor %v", tc.query, page, tc.wantLimit, tc.wantCursor)
+		}
+		if tc.wantCursor && !page.Cursor.Desc {
+			t.Errorf("parsePageParams(%q) lost the cursor's sort direction", tc.query)
+		}
+	}
+}
+
+func TestCheckOrder(t *testing.T) {
+	asc := pageParams{Cursor: &chirpCursor{ID: uuid.New()}}
+	desc := pageParams{Cursor: &chirpCursor{ID: uuid.New(), Desc: true}}
+	if err := (pageParams{}).checkOrder(true); err != nil {
+		t.Errorf("first page rejected: %v", err)
+	}
+	if asc.checkOrder(false) != nil || desc.checkOrder(true) != nil {
+		t.Error("cursor rejected by a listing sorted its way")
+	}
+	if asc.checkOrder(true) == nil || desc.checkOrder(false) == nil {
+		t.Error("cursor accepted by a listing sorted the other way")
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/aklantan/chirpy/internal/mailer"
+)
+
+// maxPasswordResetsPerHour stops forgotPassword from being used to flood
+// someone's inbox.
+const maxPasswordResetsPerHour = 3
+
+// forgotPassword emails a password reset token to the account's address. It
+// answers the same way whether or not the email belongs to an account, so it
+// cannot be used to find out which do.
+func (cfg *apiConfig) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Email string `json:"email"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	dbUser, err := cfg.db_query.GetUser(r.Context(), params.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithJSON(w, 202, nil)
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve user")
+		return
+	}
+	recent, err := cfg.db_query.CountRecentPasswordResets(r.Context(), dbUser.ID)
+	if err != nil {
+		respondWithError(w, 500, "cannot create reset token")
+		return
+	}
+	if recent >= maxPasswordResetsPerHour {
+		log.Printf("Too many password resets requested for user %s", dbUser.ID)
+		respondWithJSON(w, 202, nil)
+		return
+	}
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		respondWithError(w, 500, "cannot create reset token")
+		return
+	}
+	err = cfg.db_query.CreatePasswordReset(r.Context(), database.CreatePasswordResetParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    dbUser.ID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot create reset token")
+		return
+	}
+	cfg.sendMail(mailer.Message{
+		To:      dbUser.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password of the Chirpy account @%s.\n\n"+
+			"To choose a new password, use this reset token within the next hour:\n\n%s\n\n"+
+			"If it was not you, you can ignore this email and your password will stay the same.\n",
+			dbUser.Handle, token),
+	})
+	respondWithJSON(w, 202, nil)
+}
+
+// resetPassword sets a new password with a token from forgotPassword. Every
+// session and OAuth grant is revoked, in case the account was reset because
+// someone else had the old password.
+func (cfg *apiConfig) resetPassword(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		w.WriteHeader(400)
+		return
+	}
+	err = cfg.passwordPolicy.Check(params.Password)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	hashedPassword, err := cfg.passwords.Hash(params.Password)
+	if err != nil {
+		respondWithError(w, 500, "cannot hash password")
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "cannot reset password")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	userID, err := qtx.UsePasswordReset(r.Context(), auth.HashToken(params.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 400, "invalid or expired reset token")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "cannot reset password")
+		return
+	}
+	err = qtx.UpdatePassword(r.Context(), database.UpdatePasswordParams{ID: userID, HashedPassword: hashedPassword})
+	if err != nil {
+		respondWithError(w, 500, "cannot reset password")
+		return
+	}
+	err = qtx.InvalidatePasswordResets(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "cannot reset password")
+		return
+	}
+	err = qtx.RevokeAllSessions(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "cannot reset password")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, 500, "cannot reset password")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/aklantan/chirpy/internal/auth"
+)
+
+// loadPasswordHasher returns a hasher using the Argon2id parameters in
+// PASSWORD_ARGON2_MEMORY (in KiB), PASSWORD_ARGON2_ITERATIONS and
+// PASSWORD_ARGON2_PARALLELISM, with defaults for any that are unset. Raising
+// them makes existing hashes outdated, and each is replaced the next time
+// its user logs in.
+func loadPasswordHasher() (*auth.PasswordHasher, error) {
+	params := auth.DefaultArgon2Params
+	memory, err := envUint("PASSWORD_ARGON2_MEMORY", uint64(params.Memory), 32)
+	if err != nil {
+		return nil, err
+	}
+	iterations, err := envUint("PASSWORD_ARGON2_ITERATIONS", uint64(params.Iterations), 32)
+	if err != nil {
+		return nil, err
+	}
+	parallelism, err := envUint("PASSWORD_ARGON2_PARALLELISM", uint64(params.Parallelism), 8)
+	if err != nil {
+		return nil, err
+	}
+	params.Memory = uint32(memory)
+	params.Iterations = uint32(iterations)
+	params.Parallelism = uint8(parallelism)
+	if params.Iterations < 1 || params.Parallelism < 1 || params.Memory < 8*uint32(params.Parallelism) {
+		return nil, fmt.Errorf("invalid argon2 parameters %+v", params)
+	}
+	return &auth.PasswordHasher{Params: params}, nil
+}
+
+// loadPasswordPolicy returns the policy new passwords must satisfy. The
+// minimum length can be raised with PASSWORD_MIN_LENGTH, and
+// BREACHED_PASSWORDS_FILE names a list of breached passwords to refuse, one
+// per line.
+func loadPasswordPolicy() (auth.PasswordPolicy, error) {
+	policy := auth.DefaultPasswordPolicy
+	minLength, err := envUint("PASSWORD_MIN_LENGTH", uint64(policy.MinLength), 16)
+	if err != nil {
+		return policy, err
+	}
+	policy.MinLength = int(minLength)
+	if policy.MinLength > policy.MaxLength {
+		return policy, fmt.Errorf("PASSWORD_MIN_LENGTH must be at most %d", policy.MaxLength)
+	}
+
+	path := os.Getenv("BREACHED_PASSWORDS_FILE")
+	if path == "" {
+		return policy, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return policy, err
+	}
+	defer file.Close()
+	policy.Breached, err = auth.ReadBreachedPasswords(file)
+	if err != nil {
+		return policy, fmt.Errorf("%s: %w", path, err)
+	}
+	return policy, nil
+}
+
+func envUint(name string, fallback uint64, bitSize int) (uint64, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.ParseUint(value, 10, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return n, nil
+}
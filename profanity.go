@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/aklantan/chirpy/internal/profanity"
+)
+
+// What happens to a new or edited chirp containing profanity. Whichever is
+// chosen, profanity is masked whenever a chirp is shown, so chirps from
+// before a word was listed are masked too.
+const (
+	// profanityMask stores the chirp as written
+	profanityMask = "mask"
+	// profanityReject refuses the chirp
+	profanityReject = "reject"
+	// profanityFlag stores the chirp and flags it for a moderator
+	profanityFlag = "flag"
+)
+
+var errChirpProfane = errors.New("Chirp contains profanity")
+
+// profanityEngine holds the current profanity filter. The word list is read
+// from wordsFile if set, otherwise from the profanity_words table, and can
+// be reloaded while the server runs.
+type profanityEngine struct {
+	action    string
+	wordsFile string
+	filter    atomic.Pointer[profanity.Filter]
+}
+
+func newProfanityEngine(action, wordsFile string) (*profanityEngine, error) {
+	if action == "" {
+		action = profanityMask
+	}
+	if action != profanityMask && action != profanityReject && action != profanityFlag {
+		return nil, fmt.Errorf("unknown profanity action %q", action)
+	}
+	engine := &profanityEngine{action: action, wordsFile: wordsFile}
+	engine.filter.Store(profanity.New(nil))
+	return engine, nil
+}
+
+// load reads the word list and swaps in a filter for it. Requests already
+// using the old filter finish with it.
+func (e *profanityEngine) load(ctx context.Context, q *database.Queries) (int, error) {
+	var words []string
+	var err error
+	if e.wordsFile != "" {
+		words, err = readProfanityFile(e.wordsFile)
+	} else {
+		words, err = q.ListProfanityWords(ctx)
+	}
+	if err != nil {
+		return 0, err
+	}
+	e.filter.Store(profanity.New(words))
+	return len(words), nil
+}
+
+func readProfanityFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	words, err := profanity.ReadWords(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return words, nil
+}
+
+func (e *profanityEngine) mask(text string) string {
+	return e.filter.Load().Mask(text)
+}
+
+// screen checks the body of a new or edited chirp. It returns
+// errChirpProfane if the chirp must be refused, or the reason to flag it
+// for review with if it must be flagged.
+func (e *profanityEngine) screen(body string) (string, error) {
+	found := e.filter.Load().Find(body)
+	if len(found) == 0 || e.action == profanityMask {
+		return "", nil
+	}
+	if e.action == profanityReject {
+		return "", errChirpProfane
+	}
+	return "profanity: " + strings.Join(found, ", "), nil
+}
+
+// reloadProfanity reloads the word list, for after it has been edited.
+func (cfg *apiConfig) reloadProfanity(w http.ResponseWriter, r *http.Request) {
+	count, err := cfg.profanity.load(r.Context(), cfg.db_query)
+	if err != nil {
+		log.Printf("Error loading profanity words: %s", err)
+		respondWithError(w, 500, "cannot load profanity words")
+		return
+	}
+	respondWithJSON(w, 200, struct {
+		Words int `json:"words"`
+	}{Words: count})
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/aklantan/chirpy/internal/handles"
+	"github.com/google/uuid"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxAvatarURLLength   = 2048
+)
+
+// profileResponse is the public view of a user. It deliberately leaves out
+// the email address, which is only used to log in.
+type profileResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Handle      string    `json:"handle"`
+	DisplayName string    `json:"display_name"`
+	Bio         string    `json:"bio"`
+	AvatarURL   string    `json:"avatar_url"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func userToProfile(dbUser database.User) profileResponse {
+	return profileResponse{
+		ID:          dbUser.ID,
+		Handle:      dbUser.Handle,
+		DisplayName: dbUser.DisplayName,
+		Bio:         dbUser.Bio,
+		AvatarURL:   dbUser.AvatarUrl,
+		CreatedAt:   dbUser.CreatedAt,
+	}
+}
+
+func (cfg *apiConfig) getProfile(w http.ResponseWriter, r *http.Request) {
+	dbUser, err := cfg.db_query.GetUserByHandle(r.Context(), handles.Normalize(r.PathValue("handle")))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "user not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve user")
+		return
+	}
+	respondWithJSON(w, 200, userToProfile(dbUser))
+}
+
+// updateProfile changes only the profile fields present in the request body;
+// fields that are left out keep their current value.
+func (cfg *apiConfig) updateProfile(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Handle      *string `json:"handle"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
+		AvatarURL   *string `json:"avatar_url"`
+	}
+
+	jwtUser, ok := cfg.authorize(w, r, auth.ScopeProfileWrite)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	update := database.UpdateProfileParams{ID: jwtUser}
+	if params.Handle != nil {
+		handle := handles.Normalize(*params.Handle)
+		if !handles.Valid(handle) {
+			respondWithError(w, 400, "handle must be 3 to 30 letters, digits or underscores")
+			return
+		}
+		update.Handle = sql.NullString{String: handle, Valid: true}
+	}
+	if params.DisplayName != nil {
+		if utf8.RuneCountInString(*params.DisplayName) > maxDisplayNameLength {
+			respondWithError(w, 400, "display_name must be at most 50 characters")
+			return
+		}
+		update.DisplayName = sql.NullString{String: *params.DisplayName, Valid: true}
+	}
+	if params.Bio != nil {
+		if utf8.RuneCountInString(*params.Bio) > maxBioLength {
+			respondWithError(w, 400, "bio must be at most 160 characters")
+			return
+		}
+		update.Bio = sql.NullString{String: *params.Bio, Valid: true}
+	}
+	if params.AvatarURL != nil {
+		if *params.AvatarURL != "" && !validAvatarURL(*params.AvatarURL) {
+			respondWithError(w, 400, "avatar_url must be an https URL of at most 2048 characters")
+			return
+		}
+		update.AvatarUrl = sql.NullString{String: *params.AvatarURL, Valid: true}
+	}
+
+	dbUser, err := cfg.db_query.UpdateProfile(r.Context(), update)
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "handle already taken")
+		return
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "user not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "cannot update profile")
+		return
+	}
+	respondWithJSON(w, 200, userToProfile(dbUser))
+}
+
+// validAvatarURL accepts absolute https URLs only, so profiles never embed
+// images over plain http or from other schemes.
+func validAvatarURL(raw string) bool {
+	if len(raw) > maxAvatarURLLength {
+		return false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	return u.Scheme == "https" && u.Host != "" && u.User == nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// sharedChirp resolves the chirp a new chirp rechirps or quotes. Sharing a
+// plain rechirp shares the chirp it points at instead, so originals never
+// nest more than one level deep.
+func (cfg *apiConfig) sharedChirp(w http.ResponseWriter, r *http.Request, userID uuid.UUID, chirpID *uuid.UUID) (uuid.NullUUID, bool) {
+	if chirpID == nil {
+		return uuid.NullUUID{}, true
+	}
+	original, err := cfg.db_query.GetChirp(r.Context(), *chirpID)
+	if err != nil {
+		respondWithError(w, 404, "Shared chirp not found")
+		return uuid.NullUUID{}, false
+	}
+	if original.RechirpOf.Valid {
+		original, err = cfg.db_query.GetChirp(r.Context(), original.RechirpOf.UUID)
+		if err != nil {
+			respondWithError(w, 404, "Shared chirp not found")
+			return uuid.NullUUID{}, false
+		}
+	}
+	if original.DeletedAt.Valid {
+		respondWithError(w, 400, "Cannot share a deleted chirp")
+		return uuid.NullUUID{}, false
+	}
+	if original.HiddenAt.Valid {
+		respondWithError(w, 404, "Shared chirp not found")
+		return uuid.NullUUID{}, false
+	}
+	blocked, err := cfg.isBlockedBy(r.Context(), original.UserID, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve shared chirp")
+		return uuid.NullUUID{}, false
+	}
+	if blocked {
+		respondWithError(w, 404, "Shared chirp not found")
+		return uuid.NullUUID{}, false
+	}
+	return uuid.NullUUID{UUID: original.ID, Valid: true}, true
+}
+
+// expandOriginals embeds the shared chirp into every rechirp and quote on the
+// page using one lookup. An original that has since been deleted is embedded
+// as a deleted placeholder. Originals viewer may not see or has muted are
+// not embedded.
+func (cfg *apiConfig) expandOriginals(ctx context.Context, viewer uuid.NullUUID, chirps []chirpResponse) error {
+	ids := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if chirp.RechirpOf != nil {
+			ids = append(ids, *chirp.RechirpOf)
+		}
+		if chirp.QuoteOf != nil {
+			ids = append(ids, *chirp.QuoteOf)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	originals, err := cfg.db_query.GetVisibleChirpsByIDs(ctx, database.GetVisibleChirpsByIDsParams{Ids: ids, ViewerID: viewer})
+	if err != nil {
+		return err
+	}
+	byID := map[uuid.UUID]chirpResponse{}
+	for _, original := range originals {
+		byID[original.ID] = cfg.chirpToResponse(original)
+	}
+	for i := range chirps {
+		originalID := chirps[i].RechirpOf
+		if originalID == nil {
+			originalID = chirps[i].QuoteOf
+		}
+		if originalID == nil {
+			continue
+		}
+		if original, ok := byID[*originalID]; ok {
+			chirps[i].Original = &original
+		}
+	}
+	return nil
+}
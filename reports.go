@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxReportNoteLength = 500
+
+// reportReasons are the categories a report can be filed under.
+var reportReasons = map[string]bool{
+	"spam":           true,
+	"harassment":     true,
+	"hate":           true,
+	"violence":       true,
+	"sexual":         true,
+	"self_harm":      true,
+	"misinformation": true,
+	"other":          true,
+}
+
+type reportResponse struct {
+	ID         uuid.UUID `json:"id"`
+	ChirpID    uuid.UUID `json:"chirp_id"`
+	ReporterID uuid.UUID `json:"reporter_id"`
+	Reason     string    `json:"reason"`
+	Note       string    `json:"note"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func reportToResponse(report database.Report) reportResponse {
+	return reportResponse{
+		ID:         report.ID,
+		ChirpID:    report.ChirpID,
+		ReporterID: report.ReporterID,
+		Reason:     report.Reason,
+		Note:       report.Note,
+		CreatedAt:  report.CreatedAt,
+	}
+}
+
+// reportChirp files a report against a chirp for moderators to review. Each
+// user can report a chirp once.
+func (cfg *apiConfig) reportChirp(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Reason string `json:"reason"`
+		Note   string `json:"note"`
+	}
+
+	userID, ok := cfg.authorize(w, r, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID format")
+		return
+	}
+	chirp, err := cfg.db_query.GetChirp(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid || chirp.HiddenAt.Valid {
+		respondWithError(w, 404, "chirp not found")
+		return
+	}
+	if chirp.UserID == userID {
+		respondWithError(w, 400, "you cannot report your own chirp")
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		w.WriteHeader(400)
+		return
+	}
+	if !reportReasons[params.Reason] {
+		respondWithError(w, 400, "unknown report reason")
+		return
+	}
+	if utf8.RuneCountInString(params.Note) > maxReportNoteLength {
+		respondWithError(w, 400, "note is too long")
+		return
+	}
+	if params.Reason == "other" && params.Note == "" {
+		respondWithError(w, 400, "a note is required when the reason is other")
+		return
+	}
+
+	report, err := cfg.db_query.CreateReport(r.Context(), database.CreateReportParams{
+		ChirpID:    chirp.ID,
+		ReporterID: userID,
+		Reason:     params.Reason,
+		Note:       params.Note,
+	})
+	if isUniqueViolation(err) {
+		respondWithError(w, 409, "chirp already reported")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "cannot report chirp")
+		return
+	}
+	respondWithJSON(w, 201, reportToResponse(report))
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type revisionResponse struct {
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// editChirp replaces a chirp's body and archives the previous one. The chirp
+// row is locked for the length of the transaction so concurrent edits each
+// archive the body they actually replaced.
+func (cfg *apiConfig) editChirp(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID format")
+		return
+	}
+	jwtUser, ok := cfg.authorize(w, r, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+	if !cfg.checkCanPost(w, r, jwtUser) {
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		w.WriteHeader(400)
+		return
+	}
+	if len(params.Body) > 140 {
+		respondWithError(w, 400, "Chirp is too long")
+		return
+	}
+	flagReason, err := cfg.profanity.screen(params.Body)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "cannot edit chirp")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	chirp, err := qtx.GetChirpForUpdate(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, 404, "chirp not found")
+		return
+	}
+	if chirp.UserID != jwtUser {
+		respondWithError(w, 403, "user not authorised")
+		return
+	}
+	if chirp.RechirpOf.Valid {
+		respondWithError(w, 400, "Rechirps cannot be edited")
+		return
+	}
+	if chirp.HiddenAt.Valid {
+		respondWithError(w, 403, "Chirp has been hidden by a moderator")
+		return
+	}
+	if params.Body == "" && chirp.QuoteOf.Valid {
+		respondWithError(w, 400, "A quote needs a body")
+		return
+	}
+
+	// the archived body was current from the chirp's last edit, or its creation
+	previousFrom := chirp.CreatedAt
+	if chirp.EditedAt.Valid {
+		previousFrom = chirp.EditedAt.Time
+	}
+	_, err = qtx.CreateChirpRevision(r.Context(), database.CreateChirpRevisionParams{
+		ChirpID:   chirp.ID,
+		Body:      chirp.Body,
+		CreatedAt: previousFrom,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot edit chirp")
+		return
+	}
+	updated, err := qtx.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{ID: chirp.ID, Body: params.Body})
+	if err != nil {
+		respondWithError(w, 500, "cannot edit chirp")
+		return
+	}
+	err = saveChirpTags(r.Context(), qtx, updated.ID, updated.Body)
+	if err != nil {
+		respondWithError(w, 500, "cannot edit chirp")
+		return
+	}
+	err = saveChirpMentions(r.Context(), qtx, updated)
+	if err != nil {
+		respondWithError(w, 500, "cannot edit chirp")
+		return
+	}
+	if flagReason != "" {
+		err = qtx.CreateChirpFlag(r.Context(), database.CreateChirpFlagParams{ChirpID: updated.ID, Reason: flagReason})
+		if err != nil {
+			respondWithError(w, 500, "cannot edit chirp")
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, 500, "cannot edit chirp")
+		return
+	}
+
+	respBody := []chirpResponse{cfg.chirpToResponse(updated)}
+	err = cfg.hydrateChirps(r.Context(), uuid.NullUUID{UUID: jwtUser, Valid: true}, respBody)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve chirp details")
+		return
+	}
+	respondWithJSON(w, 200, respBody[0])
+}
+
+// getChirpRevisions lists the bodies a chirp has had before its current one,
+// oldest first.
+func (cfg *apiConfig) getChirpRevisions(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID format")
+		return
+	}
+	chirp, err := cfg.db_query.GetChirp(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid || chirp.HiddenAt.Valid {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	visible, err := cfg.chirpVisibleTo(r.Context(), chirp, cfg.viewer(r))
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve revisions")
+		return
+	}
+	if !visible {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	revisions, err := cfg.db_query.ListChirpRevisions(r.Context(), chirp.ID)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve revisions")
+		return
+	}
+
+	responseRevisions := []revisionResponse{}
+	for _, revision := range revisions {
+		responseRevisions = append(responseRevisions, revisionResponse{
+			Body:       cfg.profanity.mask(revision.Body),
+			CreatedAt:  revision.CreatedAt,
+			ReplacedAt: revision.ReplacedAt,
+		})
+	}
+	respondWithJSON(w, 200, responseRevisions)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type principalKey struct{}
+
+// requireRole only passes requests on to next if they are made by a user with
+// at least role, using the role claim of their access token. Personal API
+// tokens and OAuth clients never carry a role. The principal is added to the
+// request's context for next to read with principalFrom.
+func (cfg *apiConfig) requireRole(role string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// an outer requireRole has already resolved the principal
+		p, ok := principalFrom(r.Context())
+		if !ok {
+			var err error
+			p, err = cfg.principal(r)
+			if err != nil {
+				respondWithError(w, 401, "incorrect token or user")
+				return
+			}
+		}
+		if p.Scoped {
+			respondWithError(w, 403, "API tokens and OAuth clients cannot use admin endpoints")
+			return
+		}
+		if !auth.HasRole(p.Role, role) {
+			respondWithError(w, 403, role+" role required")
+			return
+		}
+		ctx := context.WithValue(r.Context(), principalKey{}, p)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// principalFrom returns the principal requireRole authorized.
+func principalFrom(ctx context.Context) (principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(principal)
+	return p, ok
+}
+
+// setUserRole lets an admin grant or take away a role. The user's existing
+// access tokens keep their old role until they expire.
+func (cfg *apiConfig) setUserRole(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Role string `json:"role"`
+	}
+
+	admin, _ := principalFrom(r.Context())
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID format")
+		return
+	}
+	if userID == admin.UserID {
+		respondWithError(w, 400, "you cannot change your own role")
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		w.WriteHeader(400)
+		return
+	}
+	if !auth.ValidRole(params.Role) {
+		respondWithError(w, 400, "unknown role")
+		return
+	}
+
+	user, err := cfg.db_query.SetUserRole(r.Context(), database.SetUserRoleParams{ID: userID, Role: params.Role})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "user not found")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "cannot set role")
+		return
+	}
+	cfg.recordAuditEvent(r, auditRoleChange, uuid.NullUUID{UUID: user.ID, Valid: true},
+		fmt.Sprintf("admin %s set the role of %s to %s", admin.UserID, user.ID, user.Role))
+	respondWithJSON(w, 200, struct {
+		ID   uuid.UUID `json:"id"`
+		Role string    `json:"role"`
+	}{ID: user.ID, Role: user.Role})
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"database/sql"
+	"html"
+	"net/http"
+	"strings"
+
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/aklantan/chirpy/internal/search"
+	"github.com/google/uuid"
+)
+
+type searchResult struct {
+	chirpResponse
+	Rank    float32 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// searchChirps runs a full-text search over chirp bodies. Results are ordered
+// by relevance and paginated with the same opaque cursors as GET /api/chirps.
+func (cfg *apiConfig) searchChirps(w http.ResponseWriter, r *http.Request) {
+	query, err := search.ParseQuery(r.URL.Query().Get("q"))
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	page, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	if err := page.checkOrder(true); err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	authorID, err := parseOptionalUUID(r.URL.Query().Get("author_id"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid author ID format")
+		return
+	}
+
+	afterCreatedAt, afterID := page.afterCursor()
+	afterRank := sql.NullFloat64{}
+	if page.Cursor != nil {
+		if page.Cursor.Rank == nil {
+			respondWithError(w, 400, "invalid cursor")
+			return
+		}
+		afterRank = sql.NullFloat64{Float64: float64(*page.Cursor.Rank), Valid: true}
+	}
+
+	viewer := cfg.viewer(r)
+	rows, err := cfg.db_query.SearchChirps(r.Context(), database.SearchChirpsParams{
+		Query:          query,
+		AuthorID:       authorID,
+		ViewerID:       viewer,
+		AfterRank:      afterRank,
+		AfterCreatedAt: afterCreatedAt,
+		AfterID:        afterID,
+		Limit:          page.Limit + 1,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot search chirps")
+		return
+	}
+	if len(rows) > int(page.Limit) {
+		rows = rows[:page.Limit]
+		last := rows[len(rows)-1]
+		setNextLink(w, r, chirpCursor{CreatedAt: last.Chirp.CreatedAt, ID: last.Chirp.ID, Rank: &last.Rank, Desc: true})
+	}
+
+	responseChirps := []chirpResponse{}
+	ids := []uuid.UUID{}
+	for _, row := range rows {
+		responseChirps = append(responseChirps, cfg.chirpToResponse(row.Chirp))
+		ids = append(ids, row.Chirp.ID)
+	}
+	err = cfg.hydrateChirps(r.Context(), viewer, responseChirps)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve chirp details")
+		return
+	}
+
+	snippets := map[uuid.UUID]string{}
+	if len(ids) > 0 {
+		highlights, err := cfg.db_query.HighlightChirps(r.Context(), database.HighlightChirpsParams{Query: query, Ids: ids})
+		if err != nil {
+			respondWithError(w, 500, "cannot highlight results")
+			return
+		}
+		for _, highlight := range highlights {
+			snippets[highlight.ID] = escapeSnippet(cfg.profanity.mask(highlight.Snippet))
+		}
+	}
+
+	results := []searchResult{}
+	for i, row := range rows {
+		results = append(results, searchResult{
+			chirpResponse: responseChirps[i],
+			Rank:          row.Rank,
+			Snippet:       snippets[row.Chirp.ID],
+		})
+	}
+	respondWithJSON(w, 200, results)
+}
+
+// escapeSnippet HTML-escapes a ts_headline snippet while keeping the <mark>
+// tags PostgreSQL wrapped around the matches, so clients can render it as-is.
+func escapeSnippet(snippet string) string {
+	marks := strings.Split(snippet, "<mark>")
+	for i, mark := range marks {
+		parts := strings.Split(mark, "</mark>")
+		for j, part := range parts {
+			parts[j] = html.EscapeString(part)
+		}
+		marks[i] = strings.Join(parts, "</mark>")
+	}
+	return strings.Join(marks, "<mark>")
+}
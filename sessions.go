@@ -0,0 +1,102 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// maxUserAgentLength caps how much of a client's User-Agent header is kept.
+const maxUserAgentLength = 512
+
+// sessionResponse describes one login. Its ID is the refresh token family, so
+// it stays the same however many times the session's token is rotated.
+type sessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+}
+
+// clientIP is the address of the client that sent r. Forwarding headers are
+// only believed from the proxies listed in TRUSTED_PROXIES.
+func (cfg *apiConfig) clientIP(r *http.Request) string {
+	return cfg.proxies.ClientIP(r)
+}
+
+func requestUserAgent(r *http.Request) string {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return userAgent
+}
+
+func (cfg *apiConfig) getSessions(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorizeSession(w, r)
+	if !ok {
+		return
+	}
+	sessions, err := cfg.db_query.ListSessions(r.Context(), jwtUser)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve sessions")
+		return
+	}
+
+	responseSessions := []sessionResponse{}
+	for _, session := range sessions {
+		responseSessions = append(responseSessions, sessionResponse{
+			ID:         session.FamilyID,
+			CreatedAt:  session.StartedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IpAddress,
+		})
+	}
+	respondWithJSON(w, 200, responseSessions)
+}
+
+// revokeSession logs one session out. Access tokens already issued to it stay
+// valid until they expire, at most accessTokenExpiry later.
+func (cfg *apiConfig) revokeSession(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorizeSession(w, r)
+	if !ok {
+		return
+	}
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid session ID format")
+		return
+	}
+	revoked, err := cfg.db_query.RevokeSession(r.Context(), database.RevokeSessionParams{
+		UserID:   jwtUser,
+		FamilyID: sessionID,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot revoke session")
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, 404, "session not found")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
+
+func (cfg *apiConfig) logoutAll(w http.ResponseWriter, r *http.Request) {
+	jwtUser, ok := cfg.authorizeSession(w, r)
+	if !ok {
+		return
+	}
+	err := cfg.db_query.RevokeAllSessions(r.Context(), jwtUser)
+	if err != nil {
+		respondWithError(w, 500, "cannot revoke sessions")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
@@ -0,0 +1,38 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (id, user_id, name, token_hash, scopes, created_at, expires_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    $5
+)
+RETURNING *;
+
+-- name: GetAPITokenByHash :one
+SELECT *
+FROM api_tokens
+WHERE token_hash = $1
+  AND revoked_at IS NULL
+  AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: ListAPITokens :many
+SELECT *
+FROM api_tokens
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC, id DESC;
+
+-- name: RevokeAPIToken :execrows
+UPDATE api_tokens
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: TouchAPIToken :exec
+-- last_used_at is only kept to the minute so that a busy bot does not turn
+-- every request into a write.
+UPDATE api_tokens
+SET last_used_at = NOW()
+WHERE id = $1
+  AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
@@ -0,0 +1,32 @@
+-- name: CreateAppeal :one
+INSERT INTO appeals (id, suspension_id, user_id, message, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    NOW()
+)
+RETURNING *;
+
+-- name: ListOpenAppeals :many
+SELECT appeals.*, suspensions.reason AS suspension_reason,
+    suspensions.created_at AS suspended_at, suspensions.ends_at AS suspension_ends_at
+FROM appeals
+JOIN suspensions ON suspensions.id = appeals.suspension_id
+WHERE appeals.resolved_at IS NULL
+  AND (sqlc.narg('after_created_at')::timestamp IS NULL
+       OR (appeals.created_at, appeals.id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY appeals.created_at ASC, appeals.id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: GetAppealForUpdate :one
+SELECT *
+FROM appeals
+WHERE id = $1
+FOR UPDATE;
+
+-- name: ResolveAppeal :exec
+UPDATE appeals
+SET resolved_at = NOW(), action_id = $2
+WHERE id = $1;
@@ -0,0 +1,10 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (id, created_at, kind, user_id, ip_address, detail)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+);
@@ -0,0 +1,39 @@
+-- name: BlockUser :exec
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: IsBlocked :one
+SELECT EXISTS (
+    SELECT 1
+    FROM blocks
+    WHERE blocker_id = $1 AND blocked_id = $2
+);
+
+-- name: MuteUser :exec
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+DELETE FROM mutes
+WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: IsMuted :one
+SELECT EXISTS (
+    SELECT 1
+    FROM mutes
+    WHERE muter_id = $1 AND muted_id = $2
+);
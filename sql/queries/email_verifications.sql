@@ -0,0 +1,37 @@
+-- name: CreateEmailVerification :exec
+-- Verification tokens are valid for a day.
+INSERT INTO email_verifications (token_hash, user_id, email, created_at, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    NOW(),
+    NOW() + INTERVAL '1 day'
+);
+
+-- name: CountRecentEmailVerifications :one
+SELECT COUNT(*)
+FROM email_verifications
+WHERE user_id = $1 AND created_at > NOW() - INTERVAL '1 hour';
+
+-- name: UseEmailVerification :one
+UPDATE email_verifications
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING user_id, email;
+
+-- name: InvalidateEmailVerifications :exec
+-- Only the latest requested address can be verified, so older tokens are
+-- invalidated whenever a new one is issued or one is used.
+UPDATE email_verifications
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: GetPendingVerificationEmail :one
+-- The address the user last asked to verify, unless that request has since
+-- been used or superseded.
+SELECT email
+FROM email_verifications
+WHERE user_id = $1 AND used_at IS NULL
+ORDER BY created_at DESC
+LIMIT 1;
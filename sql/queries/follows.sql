@@ -0,0 +1,60 @@
+-- name: FollowUser :exec
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: ListFollowers :many
+SELECT follower_id AS user_id, created_at
+FROM follows
+WHERE followee_id = sqlc.arg('user_id')
+  AND (sqlc.narg('after_created_at')::timestamp IS NULL
+       OR (created_at, follower_id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at DESC, follower_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListFollowing :many
+SELECT followee_id AS user_id, created_at
+FROM follows
+WHERE follower_id = sqlc.arg('user_id')
+  AND (sqlc.narg('after_created_at')::timestamp IS NULL
+       OR (created_at, followee_id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at DESC, followee_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListTimeline :many
+SELECT chirps.*
+FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = sqlc.arg('user_id')
+  AND chirps.deleted_at IS NULL
+  AND chirps.hidden_at IS NULL
+  AND (sqlc.narg('after_created_at')::timestamp IS NULL
+       OR (chirps.created_at, chirps.id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+  AND (chirps.user_id = sqlc.arg('user_id') OR chirps.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+  AND NOT EXISTS (
+      SELECT 1
+      FROM blocks
+      WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.arg('user_id'))
+         OR (blocks.blocker_id = sqlc.arg('user_id') AND blocks.blocked_id = chirps.user_id)
+  )
+  AND NOT EXISTS (
+      SELECT 1
+      FROM mutes
+      WHERE mutes.muter_id = sqlc.arg('user_id') AND mutes.muted_id = chirps.user_id
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: DeleteFollowsBetween :exec
+-- Blocking someone ends follows in both directions.
+DELETE FROM follows
+WHERE (follower_id = $1 AND followee_id = $2)
+   OR (follower_id = $2 AND followee_id = $1);
@@ -0,0 +1,54 @@
+-- name: LikeChirp :execrows
+INSERT INTO likes (user_id, chirp_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnlikeChirp :execrows
+DELETE FROM likes
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: IncrementLikeCount :one
+UPDATE chirps
+SET like_count = like_count + 1
+WHERE id = $1
+RETURNING like_count;
+
+-- name: DecrementLikeCount :one
+UPDATE chirps
+SET like_count = like_count - 1
+WHERE id = $1
+RETURNING like_count;
+
+-- name: ListLikedChirpIDs :many
+SELECT chirp_id
+FROM likes
+WHERE user_id = sqlc.arg('user_id')
+  AND chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: ListUserLikes :many
+SELECT sqlc.embed(chirps), likes.created_at AS liked_at
+FROM likes
+JOIN chirps ON chirps.id = likes.chirp_id
+WHERE likes.user_id = sqlc.arg('user_id')
+  AND chirps.deleted_at IS NULL
+  AND chirps.hidden_at IS NULL
+  AND (sqlc.narg('after_created_at')::timestamp IS NULL
+       OR (likes.created_at, likes.chirp_id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+  AND (chirps.user_id = sqlc.narg('viewer_id') OR chirps.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+  AND NOT EXISTS (
+      SELECT 1
+      FROM blocks
+      WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id'))
+         OR (blocks.blocker_id = sqlc.narg('viewer_id') AND blocks.blocked_id = chirps.user_id)
+  )
+  AND NOT EXISTS (
+      SELECT 1
+      FROM mutes
+      WHERE mutes.muter_id = sqlc.narg('viewer_id') AND mutes.muted_id = chirps.user_id
+  )
+ORDER BY likes.created_at DESC, likes.chirp_id DESC
+LIMIT sqlc.arg('limit');
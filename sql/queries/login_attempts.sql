@@ -0,0 +1,52 @@
+-- name: LockLoginSubject :exec
+-- Holds a lock on the subject until the end of the transaction, so that
+-- concurrent attempts on it are checked and counted one at a time. It is an
+-- advisory lock so that a subject needs no row until it first fails.
+SELECT pg_advisory_xact_lock(hashtextextended(sqlc.arg('kind')::text || ':' || sqlc.arg('subject')::text, 0));
+
+-- name: GetLoginRetryAfter :one
+-- Seconds until the subject may be tried again, or zero.
+SELECT COALESCE(MAX(GREATEST(CEIL(EXTRACT(EPOCH FROM locked_until - NOW())), 0)), 0)::integer AS retry_after
+FROM login_attempts
+WHERE kind = $1 AND subject = $2;
+
+-- name: RecordLoginFailure :one
+-- The count starts again once there has been no failure for a day.
+INSERT INTO login_attempts (kind, subject, failures, last_failed_at)
+VALUES ($1, $2, 1, NOW())
+ON CONFLICT (kind, subject) DO UPDATE
+SET failures = CASE
+        WHEN login_attempts.last_failed_at < NOW() - INTERVAL '1 day' THEN 1
+        ELSE login_attempts.failures + 1
+    END,
+    last_failed_at = NOW()
+RETURNING failures;
+
+-- name: LockLogin :exec
+INSERT INTO login_attempts (kind, subject, failures, last_failed_at, locked_until)
+VALUES (
+    sqlc.arg('kind'),
+    sqlc.arg('subject'),
+    0,
+    NOW(),
+    NOW() + sqlc.arg('lock_seconds')::integer * INTERVAL '1 second'
+)
+ON CONFLICT (kind, subject) DO UPDATE
+SET locked_until = EXCLUDED.locked_until;
+
+-- name: DeleteStaleLoginAttempts :exec
+-- A subject whose count would start again on its next failure and which is
+-- not locked out has nothing worth keeping.
+DELETE FROM login_attempts
+WHERE last_failed_at < NOW() - INTERVAL '1 day'
+  AND (locked_until IS NULL OR locked_until < NOW());
+
+-- name: ClearLoginFailures :exec
+DELETE FROM login_attempts
+WHERE kind = $1 AND subject = $2;
+
+-- name: ClearAccountLoginFailures :exec
+-- Only the account's count is cleared. Clearing the address's too would let
+-- an attacker reset it by logging in to an account of their own.
+DELETE FROM login_attempts
+WHERE kind = 'account' AND subject = $1;
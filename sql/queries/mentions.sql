@@ -0,0 +1,13 @@
+-- name: AddMention :execrows
+INSERT INTO mentions (chirp_id, user_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteStaleMentions :exec
+DELETE FROM mentions
+WHERE chirp_id = sqlc.arg('chirp_id')
+  AND NOT (user_id = ANY(sqlc.arg('keep_user_ids')::uuid[]));
@@ -0,0 +1,80 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, chirp_id, reporter_id, reason, note, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW()
+)
+RETURNING *;
+
+-- name: ListModerationQueue :many
+-- Groups open reports and automatic flags by chirp, longest waiting first.
+SELECT chirp_id,
+    COUNT(*) FILTER (WHERE kind = 'report') AS report_count,
+    COUNT(*) FILTER (WHERE kind = 'flag') AS flag_count,
+    MIN(created_at)::timestamp AS first_opened_at
+FROM (
+    SELECT chirp_id, 'report' AS kind, created_at
+    FROM reports
+    WHERE resolved_at IS NULL
+    UNION ALL
+    SELECT chirp_id, 'flag' AS kind, created_at
+    FROM chirp_flags
+    WHERE resolved_at IS NULL
+) AS open_items
+GROUP BY chirp_id
+HAVING sqlc.narg('after_created_at')::timestamp IS NULL
+    OR (MIN(created_at), chirp_id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+ORDER BY first_opened_at ASC, chirp_id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListOpenReports :many
+SELECT *
+FROM reports
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]) AND resolved_at IS NULL
+ORDER BY created_at ASC, id ASC;
+
+-- name: ListOpenChirpFlags :many
+SELECT *
+FROM chirp_flags
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]) AND resolved_at IS NULL
+ORDER BY created_at ASC, id ASC;
+
+-- name: CreateModerationAction :one
+INSERT INTO moderation_actions (id, moderator_id, action, chirp_id, user_id, note, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    NOW()
+)
+RETURNING *;
+
+-- name: ListModerationActions :many
+SELECT *
+FROM moderation_actions
+WHERE sqlc.narg('after_created_at')::timestamp IS NULL
+   OR (created_at, id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ResolveReports :exec
+UPDATE reports
+SET resolved_at = NOW(), action_id = $2
+WHERE chirp_id = $1 AND resolved_at IS NULL;
+
+-- name: ResolveChirpFlags :exec
+UPDATE chirp_flags
+SET resolved_at = NOW(), action_id = $2
+WHERE chirp_id = $1 AND resolved_at IS NULL;
+
+-- name: HideChirp :exec
+UPDATE chirps
+SET hidden_at = COALESCE(hidden_at, NOW()), updated_at = NOW()
+WHERE id = $1;
@@ -0,0 +1,41 @@
+-- name: CreateNotification :exec
+-- Does nothing if the user has already been notified of a mention in the
+-- chirp.
+INSERT INTO notifications (id, created_at, user_id, actor_id, kind, chirp_id, read_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NULL
+)
+ON CONFLICT (user_id, chirp_id) WHERE kind = 'mention' DO NOTHING;
+
+-- name: ListNotifications :many
+SELECT *
+FROM notifications
+WHERE user_id = sqlc.arg('user_id')
+  AND (NOT sqlc.arg('unread_only')::bool OR read_at IS NULL)
+  AND (sqlc.narg('after_created_at')::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*)
+FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = sqlc.arg('user_id')
+  AND read_at IS NULL
+  AND id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
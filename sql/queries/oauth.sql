@@ -0,0 +1,57 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (id, owner_id, name, secret_hash, redirect_uris, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW()
+)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT *
+FROM oauth_clients
+WHERE id = $1;
+
+-- name: ListOAuthClients :many
+SELECT *
+FROM oauth_clients
+WHERE owner_id = $1
+ORDER BY created_at DESC, id DESC;
+
+-- name: DeleteOAuthClient :execrows
+DELETE FROM oauth_clients
+WHERE id = $1 AND owner_id = $2;
+
+-- name: CreateOAuthCode :exec
+-- Authorization codes are only valid for ten minutes, the most RFC 6749
+-- recommends.
+INSERT INTO oauth_codes (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, created_at, expires_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    NOW(),
+    NOW() + INTERVAL '10 minutes'
+);
+
+-- name: GetOAuthCodeForUpdate :one
+SELECT *, expires_at <= NOW() AS expired
+FROM oauth_codes
+WHERE code_hash = $1
+FOR UPDATE;
+
+-- name: UseOAuthCode :exec
+UPDATE oauth_codes
+SET used_at = NOW(), family_id = $2
+WHERE code_hash = $1;
+
+-- name: GetRefreshToken :one
+SELECT *, (revoked_at IS NULL AND expires_at > NOW())::boolean AS active
+FROM refresh_tokens
+WHERE token = $1;
@@ -0,0 +1,27 @@
+-- name: CreatePasswordReset :exec
+-- Reset tokens are valid for an hour.
+INSERT INTO password_resets (token_hash, user_id, created_at, expires_at)
+VALUES (
+    $1,
+    $2,
+    NOW(),
+    NOW() + INTERVAL '1 hour'
+);
+
+-- name: CountRecentPasswordResets :one
+SELECT COUNT(*)
+FROM password_resets
+WHERE user_id = $1 AND created_at > NOW() - INTERVAL '1 hour';
+
+-- name: UsePasswordReset :one
+-- Marking the token used in the same statement that checks it means two
+-- requests cannot both use one token.
+UPDATE password_resets
+SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING user_id;
+
+-- name: InvalidatePasswordResets :exec
+UPDATE password_resets
+SET used_at = NOW()
+WHERE user_id = $1 AND used_at IS NULL;
@@ -0,0 +1,13 @@
+-- name: ListProfanityWords :many
+SELECT word
+FROM profanity_words
+ORDER BY word;
+
+-- name: CreateChirpFlag :exec
+INSERT INTO chirp_flags (id, chirp_id, reason, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    NOW()
+);
@@ -0,0 +1,35 @@
+-- name: SearchChirps :many
+-- The to_tsvector expression must match idx_chirps_body_search for the GIN
+-- index to be used.
+SELECT sqlc.embed(chirps), ranked.rank
+FROM chirps
+CROSS JOIN LATERAL (
+    SELECT ts_rank(to_tsvector('english', chirps.body), to_tsquery('english', sqlc.arg('query'))) AS rank
+) AS ranked
+WHERE to_tsvector('english', chirps.body) @@ to_tsquery('english', sqlc.arg('query'))
+  AND chirps.deleted_at IS NULL
+  AND chirps.hidden_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id'))
+  AND (chirps.user_id = sqlc.narg('viewer_id') OR chirps.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+  AND NOT EXISTS (
+      SELECT 1
+      FROM blocks
+      WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id'))
+         OR (blocks.blocker_id = sqlc.narg('viewer_id') AND blocks.blocked_id = chirps.user_id)
+  )
+  AND NOT EXISTS (
+      SELECT 1
+      FROM mutes
+      WHERE mutes.muter_id = sqlc.narg('viewer_id') AND mutes.muted_id = chirps.user_id
+  )
+  AND (sqlc.narg('after_rank')::real IS NULL
+       OR (ranked.rank, chirps.created_at, chirps.id) < (sqlc.narg('after_rank')::real, sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+ORDER BY ranked.rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: HighlightChirps :many
+SELECT id,
+    ts_headline('english', body, to_tsquery('english', sqlc.arg('query')),
+        'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MinWords=5, MaxWords=20')::text AS snippet
+FROM chirps
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
@@ -0,0 +1,34 @@
+-- name: ListSessions :many
+-- One row per session that still has a live refresh token, most recently
+-- used first. A session started when its family's first token was issued.
+-- Refresh tokens held by OAuth clients are not sessions.
+SELECT live.family_id,
+       started.started_at::timestamp AS started_at,
+       live.last_used_at,
+       live.user_agent,
+       live.ip_address,
+       live.expires_at
+FROM refresh_tokens live
+JOIN (
+    SELECT family_id, MIN(created_at) AS started_at
+    FROM refresh_tokens
+    WHERE refresh_tokens.user_id = $1
+    GROUP BY family_id
+) started ON started.family_id = live.family_id
+WHERE live.user_id = $1
+  AND live.client_id IS NULL
+  AND live.revoked_at IS NULL
+  AND live.expires_at > NOW()
+ORDER BY live.last_used_at DESC, live.family_id;
+
+-- name: RevokeSession :execrows
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND family_id = $2 AND client_id IS NULL AND revoked_at IS NULL;
+
+-- name: RevokeAllSessions :exec
+-- Logging out everywhere also revokes the refresh tokens of every OAuth
+-- client the user has authorized.
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
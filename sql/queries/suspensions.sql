@@ -0,0 +1,33 @@
+-- name: CreateSuspension :one
+-- A null duration suspends the user permanently.
+INSERT INTO suspensions (id, user_id, moderator_id, kind, reason, created_at, ends_at)
+VALUES (
+    gen_random_uuid(),
+    sqlc.arg('user_id'),
+    sqlc.arg('moderator_id'),
+    sqlc.arg('kind'),
+    sqlc.arg('reason'),
+    NOW(),
+    NOW() + make_interval(secs => sqlc.narg('duration_seconds')::int)
+)
+RETURNING *;
+
+-- name: GetActiveSuspension :one
+-- Returns the suspension that ends last when several overlap.
+SELECT *
+FROM suspensions
+WHERE user_id = $1 AND kind = $2 AND lifted_at IS NULL
+  AND (ends_at IS NULL OR ends_at > NOW())
+ORDER BY ends_at DESC NULLS FIRST
+LIMIT 1;
+
+-- name: LiftSuspension :exec
+UPDATE suspensions
+SET lifted_at = NOW()
+WHERE id = $1 AND lifted_at IS NULL;
+
+-- name: LiftActiveSuspensions :execrows
+UPDATE suspensions
+SET lifted_at = NOW()
+WHERE user_id = $1 AND kind = $2 AND lifted_at IS NULL
+  AND (ends_at IS NULL OR ends_at > NOW());
@@ -0,0 +1,70 @@
+-- name: UpsertTag :one
+INSERT INTO tags (id, name, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    NOW()
+)
+ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+RETURNING *;
+
+-- name: AddChirpTag :exec
+INSERT INTO chirp_tags (chirp_id, tag_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpTags :exec
+DELETE FROM chirp_tags
+WHERE chirp_id = $1;
+
+-- name: DeleteStaleChirpTags :exec
+DELETE FROM chirp_tags
+WHERE chirp_id = sqlc.arg('chirp_id')
+  AND NOT (tag_id = ANY(sqlc.arg('keep_tag_ids')::uuid[]));
+
+-- name: ListTagChirps :many
+SELECT chirps.*
+FROM chirps
+JOIN chirp_tags ON chirp_tags.chirp_id = chirps.id
+JOIN tags ON tags.id = chirp_tags.tag_id
+WHERE tags.name = sqlc.arg('tag')
+  AND chirps.deleted_at IS NULL
+  AND chirps.hidden_at IS NULL
+  AND (sqlc.narg('after_created_at')::timestamp IS NULL
+       OR (chirps.created_at, chirps.id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+  AND (chirps.user_id = sqlc.narg('viewer_id') OR chirps.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+  AND NOT EXISTS (
+      SELECT 1
+      FROM blocks
+      WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id'))
+         OR (blocks.blocker_id = sqlc.narg('viewer_id') AND blocks.blocked_id = chirps.user_id)
+  )
+  AND NOT EXISTS (
+      SELECT 1
+      FROM mutes
+      WHERE mutes.muter_id = sqlc.narg('viewer_id') AND mutes.muted_id = chirps.user_id
+  )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: CountTagUses :many
+-- Counts each tag's uses in the recent window and in the rest of the whole
+-- window before it, for tags used at least once recently. Chirps nobody
+-- else can see, because they are deleted, hidden or shadow banned, do not
+-- count.
+SELECT tags.name,
+    COUNT(*) FILTER (WHERE chirp_tags.created_at >= NOW() - make_interval(secs => sqlc.arg('recent_seconds')::int)) AS recent_uses,
+    COUNT(*) FILTER (WHERE chirp_tags.created_at < NOW() - make_interval(secs => sqlc.arg('recent_seconds')::int)) AS baseline_uses
+FROM chirp_tags
+JOIN tags ON tags.id = chirp_tags.tag_id
+JOIN chirps ON chirps.id = chirp_tags.chirp_id
+WHERE chirp_tags.created_at >= NOW() - make_interval(secs => sqlc.arg('window_seconds')::int)
+  AND chirps.deleted_at IS NULL
+  AND chirps.hidden_at IS NULL
+  AND chirps.user_id NOT IN (SELECT user_id FROM shadow_banned_users)
+GROUP BY tags.name
+HAVING COUNT(*) FILTER (WHERE chirp_tags.created_at >= NOW() - make_interval(secs => sqlc.arg('recent_seconds')::int)) > 0;
@@ -0,0 +1,60 @@
+-- name: StartTOTPEnrollment :one
+-- Replaces any unconfirmed secret. Returns no row if 2FA is already enabled.
+INSERT INTO user_totp (user_id, secret, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret, created_at = NOW(), last_used_step = 0
+WHERE user_totp.confirmed_at IS NULL
+RETURNING *;
+
+-- name: GetUserTOTP :one
+SELECT *
+FROM user_totp
+WHERE user_id = $1;
+
+-- name: GetUserTOTPForUpdate :one
+SELECT *
+FROM user_totp
+WHERE user_id = $1
+FOR UPDATE;
+
+-- name: ConfirmTOTP :exec
+UPDATE user_totp
+SET confirmed_at = NOW(), last_used_step = $2
+WHERE user_id = $1;
+
+-- name: SetTOTPLastUsedStep :exec
+UPDATE user_totp
+SET last_used_step = $2
+WHERE user_id = $1;
+
+-- name: DeleteUserTOTP :exec
+DELETE FROM user_totp
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (id, user_id, code_hash, created_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    NOW()
+);
+
+-- name: ListUnusedRecoveryCodes :many
+SELECT id, code_hash
+FROM recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = NOW()
+WHERE id = $1 AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
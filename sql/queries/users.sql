@@ -1,12 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email,hashed_password)
+INSERT INTO users (id, created_at, updated_at, email,hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
-    
+    $2,
+    $3
 )
 RETURNING *;
 
@@ -14,20 +14,64 @@ RETURNING *;
 DELETE FROM users;
 
 -- name: SaveChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, rechirp_of, quote_of)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5
 )
 RETURNING *;
 
--- name: GetChirps :many
+-- name: ListChirpsAsc :many
 SELECT *
 FROM chirps
-ORDER BY created_at ASC;
+WHERE deleted_at IS NULL
+  AND hidden_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+  AND (sqlc.narg('after_created_at')::timestamp IS NULL
+       OR (created_at, id) > (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+  AND (chirps.user_id = sqlc.narg('viewer_id') OR chirps.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+  AND NOT EXISTS (
+      SELECT 1
+      FROM blocks
+      WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id'))
+         OR (blocks.blocker_id = sqlc.narg('viewer_id') AND blocks.blocked_id = chirps.user_id)
+  )
+  AND (sqlc.narg('author_id')::uuid IS NOT NULL OR NOT EXISTS (
+      SELECT 1
+      FROM mutes
+      WHERE mutes.muter_id = sqlc.narg('viewer_id') AND mutes.muted_id = chirps.user_id
+  ))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
+
+-- name: ListChirpsDesc :many
+SELECT *
+FROM chirps
+WHERE deleted_at IS NULL
+  AND hidden_at IS NULL
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+  AND (sqlc.narg('after_created_at')::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg('after_created_at')::timestamp, sqlc.narg('after_id')::uuid))
+  AND (chirps.user_id = sqlc.narg('viewer_id') OR chirps.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+  AND NOT EXISTS (
+      SELECT 1
+      FROM blocks
+      WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id'))
+         OR (blocks.blocker_id = sqlc.narg('viewer_id') AND blocks.blocked_id = chirps.user_id)
+  )
+  AND (sqlc.narg('author_id')::uuid IS NOT NULL OR NOT EXISTS (
+      SELECT 1
+      FROM mutes
+      WHERE mutes.muter_id = sqlc.narg('viewer_id') AND mutes.muted_id = chirps.user_id
+  ))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
 
 -- name: GetChirp :one
 SELECT *
@@ -39,15 +83,31 @@ SELECT *
 FROM users
 WHERE email = $1;
 
+-- name: GetUserByID :one
+SELECT *
+FROM users
+WHERE id = $1;
+
+-- name: GetUsersByHandles :many
+SELECT id, handle
+FROM users
+WHERE handle = ANY(sqlc.arg('handles')::text[]);
+
 -- name: AddRefreshToken :one
-INSERT INTO refresh_tokens(token,created_at,updated_at,user_id,expires_at,revoked_at)
+INSERT INTO refresh_tokens(token,created_at,updated_at,user_id,expires_at,revoked_at,family_id,user_agent,ip_address,last_used_at,client_id,scopes)
 VALUES(
     $1,
     NOW(),
     NOW(),
     $2,
     $3,
-    NULL
+    NULL,
+    $4,
+    $5,
+    $6,
+    NOW(),
+    $7,
+    $8
 )
 RETURNING *;
 
@@ -61,12 +121,192 @@ UPDATE refresh_tokens
 SET updated_at = NOW(), revoked_at = NOW()
 WHERE token = $1;
 
--- name: UpdateEmailandPassword :one
+-- name: GetRefreshTokenForUpdate :one
+SELECT *
+FROM refresh_tokens
+WHERE token = $1
+FOR UPDATE;
+
+-- name: RotateRefreshToken :execrows
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = NOW(), replaced_by = $2
+WHERE token = $1 AND revoked_at IS NULL AND expires_at > NOW();
+
+-- name: RevokeRefreshTokenFamily :exec
+-- Called when a rotated token is presented again: whoever holds the family's
+-- live token may have stolen it, so the whole family is logged out.
+UPDATE refresh_tokens
+SET updated_at = NOW(), revoked_at = COALESCE(revoked_at, NOW())
+WHERE family_id = $1;
+
+-- name: UpdatePassword :exec
 UPDATE users
-SET email = $1, hashed_password = $2
-WHERE id = $3
-RETURNING *;
+SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: UpdatePasswordHash :exec
+-- Only replaces the hash the new one was computed from, so rehashing on
+-- login cannot undo a password change made at the same time.
+UPDATE users
+SET hashed_password = sqlc.arg('hashed_password')
+WHERE id = sqlc.arg('id') AND hashed_password = sqlc.arg('old_hashed_password');
 
 -- name: DeleteChirp :exec
-DELETE FROM chirps
-WHERE id = $1;
\ No newline at end of file
+-- Chirps are tombstoned rather than removed so that replies keep their place
+-- in the thread.
+UPDATE chirps
+SET body = '', updated_at = NOW(), deleted_at = NOW()
+WHERE id = $1;
+
+-- name: GetChirpForUpdate :one
+SELECT *
+FROM chirps
+WHERE id = $1
+FOR UPDATE;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2, updated_at = NOW(), edited_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetChirpsByIDs :many
+SELECT *
+FROM chirps
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: GetVisibleChirpsByIDs :many
+-- GetChirpsByIDs without the chirps the viewer may not see or has muted.
+SELECT *
+FROM chirps
+WHERE id = ANY(sqlc.arg('ids')::uuid[])
+  AND (chirps.user_id = sqlc.narg('viewer_id') OR chirps.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+  AND NOT EXISTS (
+      SELECT 1
+      FROM blocks
+      WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id'))
+         OR (blocks.blocker_id = sqlc.narg('viewer_id') AND blocks.blocked_id = chirps.user_id)
+  )
+  AND NOT EXISTS (
+      SELECT 1
+      FROM mutes
+      WHERE mutes.muter_id = sqlc.narg('viewer_id') AND mutes.muted_id = chirps.user_id
+  );
+
+-- name: GetChirpAncestors :many
+-- The walk up the thread stops at the first chirp the viewer may not see or
+-- has muted.
+WITH RECURSIVE ancestors AS (
+    SELECT parent.id AS ancestor_id, parent.in_reply_to AS next_id, 1 AS depth
+    FROM chirps parent
+    JOIN chirps child ON child.in_reply_to = parent.id
+    WHERE child.id = sqlc.arg('id')::uuid
+      AND (parent.user_id = sqlc.narg('viewer_id') OR parent.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+      AND NOT EXISTS (
+          SELECT 1
+          FROM blocks
+          WHERE (blocks.blocker_id = parent.user_id AND blocks.blocked_id = sqlc.narg('viewer_id'))
+             OR (blocks.blocker_id = sqlc.narg('viewer_id') AND blocks.blocked_id = parent.user_id)
+      )
+      AND NOT EXISTS (
+          SELECT 1
+          FROM mutes
+          WHERE mutes.muter_id = sqlc.narg('viewer_id') AND mutes.muted_id = parent.user_id
+      )
+    UNION ALL
+    SELECT parent.id, parent.in_reply_to, ancestors.depth + 1
+    FROM chirps parent
+    JOIN ancestors ON ancestors.next_id = parent.id
+    WHERE (parent.user_id = sqlc.narg('viewer_id') OR parent.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+      AND NOT EXISTS (
+          SELECT 1
+          FROM blocks
+          WHERE (blocks.blocker_id = parent.user_id AND blocks.blocked_id = sqlc.narg('viewer_id'))
+             OR (blocks.blocker_id = sqlc.narg('viewer_id') AND blocks.blocked_id = parent.user_id)
+      )
+      AND NOT EXISTS (
+          SELECT 1
+          FROM mutes
+          WHERE mutes.muter_id = sqlc.narg('viewer_id') AND mutes.muted_id = parent.user_id
+      )
+)
+SELECT sqlc.embed(chirps), depth
+FROM ancestors
+JOIN chirps ON chirps.id = ancestor_id
+ORDER BY depth DESC;
+
+-- name: GetChirpDescendants :many
+-- Replies the viewer may not see or has muted are left out along with the
+-- replies to them.
+WITH RECURSIVE descendants AS (
+    SELECT chirps.id AS descendant_id, 1 AS depth
+    FROM chirps
+    WHERE chirps.in_reply_to = sqlc.arg('id')::uuid
+      AND (chirps.user_id = sqlc.narg('viewer_id') OR chirps.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+      AND NOT EXISTS (
+          SELECT 1
+          FROM blocks
+          WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id'))
+             OR (blocks.blocker_id = sqlc.narg('viewer_id') AND blocks.blocked_id = chirps.user_id)
+      )
+      AND NOT EXISTS (
+          SELECT 1
+          FROM mutes
+          WHERE mutes.muter_id = sqlc.narg('viewer_id') AND mutes.muted_id = chirps.user_id
+      )
+    UNION ALL
+    SELECT chirps.id, descendants.depth + 1
+    FROM chirps
+    JOIN descendants ON chirps.in_reply_to = descendants.descendant_id
+    WHERE descendants.depth < sqlc.arg('max_depth')::int
+      AND (chirps.user_id = sqlc.narg('viewer_id') OR chirps.user_id NOT IN (SELECT user_id FROM shadow_banned_users))
+      AND NOT EXISTS (
+          SELECT 1
+          FROM blocks
+          WHERE (blocks.blocker_id = chirps.user_id AND blocks.blocked_id = sqlc.narg('viewer_id'))
+             OR (blocks.blocker_id = sqlc.narg('viewer_id') AND blocks.blocked_id = chirps.user_id)
+      )
+      AND NOT EXISTS (
+          SELECT 1
+          FROM mutes
+          WHERE mutes.muter_id = sqlc.narg('viewer_id') AND mutes.muted_id = chirps.user_id
+      )
+)
+SELECT sqlc.embed(chirps), depth
+FROM descendants
+JOIN chirps ON chirps.id = descendant_id
+ORDER BY depth ASC, chirps.created_at ASC, chirps.id ASC;
+
+-- name: GetUserByHandle :one
+SELECT *
+FROM users
+WHERE handle = $1;
+
+-- name: UpdateProfile :one
+UPDATE users
+SET handle = COALESCE(sqlc.narg('handle'), handle),
+    display_name = COALESCE(sqlc.narg('display_name'), display_name),
+    bio = COALESCE(sqlc.narg('bio'), bio),
+    avatar_url = COALESCE(sqlc.narg('avatar_url'), avatar_url),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
+RETURNING *;
+
+-- name: VerifyEmail :one
+-- Marks the account verified, switching it to email if this verified a
+-- change of address.
+UPDATE users
+SET email = $2, verified_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CountUsersWithRole :one
+SELECT COUNT(*)
+FROM users
+WHERE role = $1;
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE chirps
+    ADD COLUMN rechirp_of UUID,
+    ADD COLUMN quote_of UUID,
+    ADD CONSTRAINT fk_rechirp_of
+    FOREIGN KEY (rechirp_of) REFERENCES chirps(id)
+    ON DELETE SET NULL,
+    ADD CONSTRAINT fk_quote_of
+    FOREIGN KEY (quote_of) REFERENCES chirps(id)
+    ON DELETE SET NULL,
+    ADD CONSTRAINT rechirp_or_quote CHECK (rechirp_of IS NULL OR quote_of IS NULL);
+
+-- a user can only have one live plain rechirp of any chirp
+CREATE UNIQUE INDEX idx_chirps_user_id_rechirp_of ON chirps (user_id, rechirp_of)
+WHERE rechirp_of IS NOT NULL AND deleted_at IS NULL;
+
+-- +goose Down
+DROP INDEX idx_chirps_user_id_rechirp_of;
+ALTER TABLE chirps
+    DROP CONSTRAINT rechirp_or_quote,
+    DROP COLUMN quote_of,
+    DROP COLUMN rechirp_of;
@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE chirp_revisions(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    chirp_id UUID NOT NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    replaced_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_chirp_id
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX idx_chirp_revisions_chirp_id ON chirp_revisions (chirp_id, replaced_at);
+
+ALTER TABLE chirps ADD COLUMN edited_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE chirps DROP COLUMN edited_at;
+DROP TABLE chirp_revisions;
@@ -0,0 +1,5 @@
+-- +goose Up
+CREATE INDEX idx_chirps_body_search ON chirps USING GIN (to_tsvector('english', body));
+
+-- +goose Down
+DROP INDEX idx_chirps_body_search;
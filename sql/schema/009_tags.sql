@@ -0,0 +1,26 @@
+-- +goose Up
+CREATE TABLE tags(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT UNIQUE NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE chirp_tags(
+    chirp_id UUID NOT NULL,
+    tag_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, tag_id),
+    CONSTRAINT fk_chirp_id
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_tag_id
+    FOREIGN KEY (tag_id) REFERENCES tags(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX idx_chirp_tags_tag_id_created_at ON chirp_tags (tag_id, created_at);
+CREATE INDEX idx_chirp_tags_created_at ON chirp_tags (created_at);
+
+-- +goose Down
+DROP TABLE chirp_tags;
+DROP TABLE tags;
@@ -0,0 +1,48 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN handle TEXT;
+UPDATE users SET handle = 'user_' || substr(replace(id::text, '-', ''), 1, 12);
+ALTER TABLE users
+    ALTER COLUMN handle SET NOT NULL,
+    ADD CONSTRAINT users_handle_key UNIQUE (handle);
+
+CREATE TABLE mentions(
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chirp_id, user_id),
+    CONSTRAINT fk_chirp_id
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id) REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX idx_mentions_user_id ON mentions (user_id, created_at);
+
+CREATE TABLE notifications(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    actor_id UUID NOT NULL,
+    kind TEXT NOT NULL,
+    chirp_id UUID,
+    read_at TIMESTAMP,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id) REFERENCES users(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_actor_id
+    FOREIGN KEY (actor_id) REFERENCES users(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_chirp_id
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX idx_notifications_user_id_created_at ON notifications (user_id, created_at, id);
+CREATE INDEX idx_notifications_unread ON notifications (user_id) WHERE read_at IS NULL;
+
+-- +goose Down
+DROP TABLE notifications;
+DROP TABLE mentions;
+ALTER TABLE users DROP COLUMN handle;
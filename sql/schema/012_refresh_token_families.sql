@@ -0,0 +1,15 @@
+-- +goose Up
+-- every refresh token belongs to the family started by the login that issued
+-- it; rotating a token links it to its replacement
+ALTER TABLE refresh_tokens
+    ADD COLUMN family_id UUID NOT NULL DEFAULT gen_random_uuid(),
+    ADD COLUMN replaced_by TEXT;
+ALTER TABLE refresh_tokens ALTER COLUMN family_id DROP DEFAULT;
+
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+
+-- +goose Down
+DROP INDEX idx_refresh_tokens_family_id;
+ALTER TABLE refresh_tokens
+    DROP COLUMN replaced_by,
+    DROP COLUMN family_id;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- a session is a refresh token family; its live token carries the device it
+-- was last refreshed from
+ALTER TABLE refresh_tokens
+    ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+    ADD COLUMN ip_address TEXT NOT NULL DEFAULT '',
+    ADD COLUMN last_used_at TIMESTAMP;
+UPDATE refresh_tokens SET last_used_at = updated_at;
+ALTER TABLE refresh_tokens ALTER COLUMN last_used_at SET NOT NULL;
+
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id) WHERE revoked_at IS NULL;
+
+-- +goose Down
+DROP INDEX idx_refresh_tokens_user_id;
+ALTER TABLE refresh_tokens
+    DROP COLUMN last_used_at,
+    DROP COLUMN ip_address,
+    DROP COLUMN user_agent;
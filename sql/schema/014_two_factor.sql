@@ -0,0 +1,31 @@
+-- +goose Up
+-- a user has 2FA enabled once their TOTP secret is confirmed
+CREATE TABLE user_totp(
+    user_id UUID PRIMARY KEY,
+    secret TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    confirmed_at TIMESTAMP,
+    -- the newest time step a code has been accepted for; codes from it or
+    -- earlier steps are refused so an observed code cannot be replayed
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id) REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE TABLE recovery_codes(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    code_hash TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id) REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX idx_recovery_codes_user_id ON recovery_codes (user_id) WHERE used_at IS NULL;
+
+-- +goose Down
+DROP TABLE recovery_codes;
+DROP TABLE user_totp;
@@ -0,0 +1,53 @@
+-- +goose Up
+CREATE TABLE oauth_clients(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    -- NULL for public clients such as mobile and single page apps, which
+    -- cannot keep a secret and rely on PKCE alone
+    secret_hash TEXT,
+    redirect_uris TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_owner_id
+    FOREIGN KEY (owner_id) REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX idx_oauth_clients_owner_id ON oauth_clients (owner_id);
+
+CREATE TABLE oauth_codes(
+    code_hash TEXT PRIMARY KEY,
+    client_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    redirect_uri TEXT NOT NULL,
+    scopes TEXT[] NOT NULL,
+    code_challenge TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    -- the refresh token family the code was exchanged for, revoked if the
+    -- code is ever presented again
+    family_id UUID,
+    CONSTRAINT fk_client_id
+    FOREIGN KEY (client_id) REFERENCES oauth_clients(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id) REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+-- refresh tokens issued to OAuth clients are limited to the granted scopes
+ALTER TABLE refresh_tokens
+    ADD COLUMN client_id UUID,
+    ADD COLUMN scopes TEXT[],
+    ADD CONSTRAINT fk_client_id
+    FOREIGN KEY (client_id) REFERENCES oauth_clients(id)
+    ON DELETE CASCADE;
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+    DROP CONSTRAINT fk_client_id,
+    DROP COLUMN scopes,
+    DROP COLUMN client_id;
+DROP TABLE oauth_codes;
+DROP TABLE oauth_clients;
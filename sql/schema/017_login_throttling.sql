@@ -0,0 +1,31 @@
+-- +goose Up
+-- Failed logins are counted both per account, keyed by the normalized email
+-- so that unknown emails are throttled like real ones, and per client
+-- address. Keeping them here rather than in memory means every server
+-- instance sees the same counts.
+CREATE TABLE login_attempts(
+    kind TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    failures INTEGER NOT NULL,
+    last_failed_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP,
+    PRIMARY KEY (kind, subject)
+);
+
+CREATE TABLE audit_events(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMP NOT NULL,
+    kind TEXT NOT NULL,
+    user_id UUID,
+    ip_address TEXT NOT NULL,
+    detail TEXT NOT NULL,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id) REFERENCES users(id)
+    ON DELETE SET NULL
+);
+
+CREATE INDEX idx_audit_events_created_at ON audit_events (created_at);
+
+-- +goose Down
+DROP TABLE audit_events;
+DROP TABLE login_attempts;
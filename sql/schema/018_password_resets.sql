@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE password_resets(
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id) REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX idx_password_resets_user_id ON password_resets (user_id, created_at);
+
+-- +goose Down
+DROP TABLE password_resets;
@@ -0,0 +1,28 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN verified_at TIMESTAMP;
+
+-- accounts from before verification existed are trusted as they are
+UPDATE users SET verified_at = created_at;
+
+-- A verification proves the user controls email. For a new account that is
+-- the address they signed up with; for an email change it is the new
+-- address, which only replaces the old one once verified.
+CREATE TABLE email_verifications(
+    token_hash TEXT PRIMARY KEY,
+    user_id UUID NOT NULL,
+    email TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id) REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX idx_email_verifications_user_id ON email_verifications (user_id, created_at);
+
+-- +goose Down
+DROP TABLE email_verifications;
+ALTER TABLE users
+    DROP COLUMN verified_at;
@@ -0,0 +1,27 @@
+-- +goose Up
+-- The profanity word list, used unless PROFANITY_WORDS_FILE is set.
+CREATE TABLE profanity_words(
+    word TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL
+);
+
+INSERT INTO profanity_words (word, created_at)
+VALUES ('kerfuffle', NOW()), ('sharbert', NOW()), ('fornax', NOW());
+
+-- Chirps waiting for a moderator to review them.
+CREATE TABLE chirp_flags(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    chirp_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    CONSTRAINT fk_chirp_id
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX idx_chirp_flags_unresolved ON chirp_flags (created_at) WHERE resolved_at IS NULL;
+
+-- +goose Down
+DROP TABLE chirp_flags;
+DROP TABLE profanity_words;
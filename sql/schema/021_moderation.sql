@@ -0,0 +1,86 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN role TEXT NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'moderator', 'admin'));
+
+-- Hidden chirps keep their body so moderators can still review them, but
+-- nobody else sees it.
+ALTER TABLE chirps
+    ADD COLUMN hidden_at TIMESTAMP;
+
+-- Every action a moderator takes, whether or not it resolved a report.
+CREATE TABLE moderation_actions(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    moderator_id UUID,
+    action TEXT NOT NULL,
+    chirp_id UUID,
+    user_id UUID,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    CONSTRAINT fk_moderator_id
+    FOREIGN KEY (moderator_id) REFERENCES users(id)
+    ON DELETE SET NULL,
+    CONSTRAINT fk_chirp_id
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id)
+    ON DELETE SET NULL,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id) REFERENCES users(id)
+    ON DELETE SET NULL
+);
+
+CREATE INDEX idx_moderation_actions_created_at ON moderation_actions (created_at, id);
+
+CREATE TABLE reports(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    chirp_id UUID NOT NULL,
+    reporter_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    action_id UUID,
+    CONSTRAINT fk_chirp_id
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_reporter_id
+    FOREIGN KEY (reporter_id) REFERENCES users(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_action_id
+    FOREIGN KEY (action_id) REFERENCES moderation_actions(id)
+    ON DELETE SET NULL,
+    UNIQUE (chirp_id, reporter_id)
+);
+
+CREATE INDEX idx_reports_unresolved ON reports (chirp_id) WHERE resolved_at IS NULL;
+
+ALTER TABLE chirp_flags
+    ADD COLUMN action_id UUID REFERENCES moderation_actions(id) ON DELETE SET NULL;
+
+-- ends_at is NULL for a permanent suspension.
+CREATE TABLE suspensions(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    moderator_id UUID,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id) REFERENCES users(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_moderator_id
+    FOREIGN KEY (moderator_id) REFERENCES users(id)
+    ON DELETE SET NULL
+);
+
+CREATE INDEX idx_suspensions_user_id ON suspensions (user_id, created_at);
+
+-- +goose Down
+DROP TABLE suspensions;
+ALTER TABLE chirp_flags
+    DROP COLUMN action_id;
+DROP TABLE reports;
+DROP TABLE moderation_actions;
+ALTER TABLE chirps
+    DROP COLUMN hidden_at;
+ALTER TABLE users
+    DROP COLUMN role;
@@ -0,0 +1,46 @@
+-- +goose Up
+-- A shadow ban lets the user carry on as normal while hiding their chirps
+-- from everyone else.
+ALTER TABLE suspensions
+    ADD COLUMN kind TEXT NOT NULL DEFAULT 'suspension'
+    CHECK (kind IN ('suspension', 'shadow_ban')),
+    ADD COLUMN lifted_at TIMESTAMP;
+
+CREATE INDEX idx_suspensions_shadow_ban ON suspensions (user_id)
+    WHERE kind = 'shadow_ban' AND lifted_at IS NULL;
+
+CREATE VIEW shadow_banned_users AS
+SELECT DISTINCT user_id
+FROM suspensions
+WHERE kind = 'shadow_ban' AND lifted_at IS NULL
+  AND (ends_at IS NULL OR ends_at > NOW());
+
+-- Each suspension can be appealed once.
+CREATE TABLE appeals(
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    suspension_id UUID NOT NULL UNIQUE,
+    user_id UUID NOT NULL,
+    message TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    resolved_at TIMESTAMP,
+    action_id UUID,
+    CONSTRAINT fk_suspension_id
+    FOREIGN KEY (suspension_id) REFERENCES suspensions(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id) REFERENCES users(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_action_id
+    FOREIGN KEY (action_id) REFERENCES moderation_actions(id)
+    ON DELETE SET NULL
+);
+
+CREATE INDEX idx_appeals_unresolved ON appeals (created_at, id) WHERE resolved_at IS NULL;
+
+-- +goose Down
+DROP TABLE appeals;
+DROP VIEW shadow_banned_users;
+DROP INDEX idx_suspensions_shadow_ban;
+ALTER TABLE suspensions
+    DROP COLUMN lifted_at,
+    DROP COLUMN kind;
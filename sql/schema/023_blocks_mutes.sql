@@ -0,0 +1,37 @@
+-- +goose Up
+-- A blocked user cannot see, reply to, mention or follow the blocker.
+CREATE TABLE blocks(
+    blocker_id UUID NOT NULL,
+    blocked_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (blocker_id, blocked_id),
+    CONSTRAINT fk_blocker_id
+    FOREIGN KEY (blocker_id) REFERENCES users(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_blocked_id
+    FOREIGN KEY (blocked_id) REFERENCES users(id)
+    ON DELETE CASCADE,
+    CONSTRAINT no_self_block CHECK (blocker_id <> blocked_id)
+);
+
+CREATE INDEX idx_blocks_blocked_id ON blocks (blocked_id, blocker_id);
+
+-- A muted user's chirps are left out of the muter's feeds. The muted user is
+-- never told.
+CREATE TABLE mutes(
+    muter_id UUID NOT NULL,
+    muted_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (muter_id, muted_id),
+    CONSTRAINT fk_muter_id
+    FOREIGN KEY (muter_id) REFERENCES users(id)
+    ON DELETE CASCADE,
+    CONSTRAINT fk_muted_id
+    FOREIGN KEY (muted_id) REFERENCES users(id)
+    ON DELETE CASCADE,
+    CONSTRAINT no_self_mute CHECK (muter_id <> muted_id)
+);
+
+-- +goose Down
+DROP TABLE mutes;
+DROP TABLE blocks;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- A user is notified of a mention in a chirp once, however often the chirp
+-- is edited to drop the mention and put it back.
+DELETE FROM notifications a
+USING notifications b
+WHERE a.kind = 'mention' AND b.kind = 'mention'
+  AND a.user_id = b.user_id AND a.chirp_id = b.chirp_id
+  AND (a.created_at, a.id) > (b.created_at, b.id);
+
+CREATE UNIQUE INDEX idx_notifications_mention_once ON notifications (user_id, chirp_id) WHERE kind = 'mention';
+
+-- +goose Down
+DROP INDEX idx_notifications_mention_once;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- A code only records a redirect_uri if the authorization request included
+-- one, since only then must the token request repeat it (RFC 6749 section
+-- 4.1.3).
+ALTER TABLE oauth_codes ALTER COLUMN redirect_uri DROP NOT NULL;
+
+-- +goose Down
+UPDATE oauth_codes
+SET redirect_uri = ''
+WHERE redirect_uri IS NULL;
+ALTER TABLE oauth_codes ALTER COLUMN redirect_uri SET NOT NULL;
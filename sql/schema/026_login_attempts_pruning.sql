@@ -0,0 +1,11 @@
+-- +goose Up
+-- Stale login attempts are deleted as new failures are recorded, so the
+-- table only holds subjects that failed within the last day.
+DELETE FROM login_attempts
+WHERE last_failed_at < NOW() - INTERVAL '1 day'
+  AND (locked_until IS NULL OR locked_until < NOW());
+
+CREATE INDEX idx_login_attempts_last_failed_at ON login_attempts (last_failed_at);
+
+-- +goose Down
+DROP INDEX idx_login_attempts_last_failed_at;
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// Kinds of suspension. A suspended user cannot log in, refresh their session
+// or use any access token or API token they already hold. A shadow banned
+// user carries on as normal, but nobody else sees their chirps.
+const (
+	suspensionKind = "suspension"
+	shadowBanKind  = "shadow_ban"
+)
+
+// suspensionKinds maps the moderation actions that suspend a user to the
+// kind of suspension.
+var suspensionKinds = map[string]string{
+	moderationSuspend:   suspensionKind,
+	moderationShadowBan: shadowBanKind,
+}
+
+var errSuspendStaff = errors.New("moderators and admins cannot be suspended")
+
+type suspensionResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Kind      string     `json:"kind"`
+	Reason    string     `json:"reason"`
+	CreatedAt time.Time  `json:"created_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+}
+
+func suspensionToResponse(suspension database.Suspension) suspensionResponse {
+	resp := suspensionResponse{
+		ID:        suspension.ID,
+		UserID:    suspension.UserID,
+		Kind:      suspension.Kind,
+		Reason:    suspension.Reason,
+		CreatedAt: suspension.CreatedAt,
+	}
+	if suspension.EndsAt.Valid {
+		resp.EndsAt = &suspension.EndsAt.Time
+	}
+	return resp
+}
+
+// suspendUser suspends or shadow bans a user. A suspension also logs them
+// out everywhere, and authorize refuses their access and API tokens until it
+// ends; a shadow ban is meant to go unnoticed, so it does neither.
+func suspendUser(ctx context.Context, q *database.Queries, arg database.CreateSuspensionParams) (database.Suspension, error) {
+	user, err := q.GetUserByID(ctx, arg.UserID)
+	if err != nil {
+		return database.Suspension{}, err
+	}
+	if user.Role != auth.RoleUser {
+		return database.Suspension{}, errSuspendStaff
+	}
+	suspension, err := q.CreateSuspension(ctx, arg)
+	if err != nil {
+		return suspension, err
+	}
+	if arg.Kind == suspensionKind {
+		err = q.RevokeAllSessions(ctx, arg.UserID)
+	}
+	return suspension, err
+}
+
+// activeSuspension returns the user's current suspension of kind, if any.
+func (cfg *apiConfig) activeSuspension(ctx context.Context, userID uuid.UUID, kind string) (database.Suspension, bool, error) {
+	suspension, err := cfg.db_query.GetActiveSuspension(ctx, database.GetActiveSuspensionParams{UserID: userID, Kind: kind})
+	if errors.Is(err, sql.ErrNoRows) {
+		return suspension, false, nil
+	}
+	if err != nil {
+		return suspension, false, err
+	}
+	return suspension, true, nil
+}
+
+// checkNotSuspended reports whether userID may use their account. When they
+// may not, it has already responded with 403 and the account_suspended
+// error code, so clients can tell the user why and offer an appeal.
+func (cfg *apiConfig) checkNotSuspended(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	suspension, suspended, err := cfg.activeSuspension(r.Context(), userID, suspensionKind)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve user")
+		return false
+	}
+	if !suspended {
+		return true
+	}
+	resp := struct {
+		Error  string     `json:"error"`
+		Code   string     `json:"code"`
+		Reason string     `json:"reason"`
+		EndsAt *time.Time `json:"ends_at"`
+	}{
+		Error:  "account suspended",
+		Code:   "account_suspended",
+		Reason: suspension.Reason,
+	}
+	if suspension.EndsAt.Valid {
+		resp.EndsAt = &suspension.EndsAt.Time
+	}
+	respondWithJSON(w, 403, resp)
+	return false
+}
+
+// createSuspension suspends or shadow bans a user directly, rather than over
+// one of their chirps.
+func (cfg *apiConfig) createSuspension(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Kind            string `json:"kind"`
+		Reason          string `json:"reason"`
+		DurationSeconds *int32 `json:"duration_seconds"`
+	}
+
+	moderator, _ := principalFrom(r.Context())
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID format")
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		w.WriteHeader(400)
+		return
+	}
+	if params.Kind == "" {
+		params.Kind = suspensionKind
+	}
+	if params.Kind != suspensionKind && params.Kind != shadowBanKind {
+		respondWithError(w, 400, "kind must be suspension or shadow_ban")
+		return
+	}
+	if params.Reason == "" || utf8.RuneCountInString(params.Reason) > maxModerationNoteLength {
+		respondWithError(w, 400, "a reason of at most 1000 characters is required")
+		return
+	}
+	duration := sql.NullInt32{}
+	if params.DurationSeconds != nil {
+		if *params.DurationSeconds <= 0 {
+			respondWithError(w, 400, "duration_seconds must be positive")
+			return
+		}
+		duration = sql.NullInt32{Int32: *params.DurationSeconds, Valid: true}
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "cannot suspend user")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	suspension, err := suspendUser(r.Context(), qtx, database.CreateSuspensionParams{
+		UserID:          userID,
+		ModeratorID:     uuid.NullUUID{UUID: moderator.UserID, Valid: true},
+		Kind:            params.Kind,
+		Reason:          params.Reason,
+		DurationSeconds: duration,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "user not found")
+		return
+	}
+	if errors.Is(err, errSuspendStaff) {
+		respondWithError(w, 403, err.Error())
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "cannot suspend user")
+		return
+	}
+	action := moderationSuspend
+	if params.Kind == shadowBanKind {
+		action = moderationShadowBan
+	}
+	_, err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+		ModeratorID: uuid.NullUUID{UUID: moderator.UserID, Valid: true},
+		Action:      action,
+		UserID:      uuid.NullUUID{UUID: userID, Valid: true},
+		Note:        params.Reason,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot suspend user")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, 500, "cannot suspend user")
+		return
+	}
+	respondWithJSON(w, 201, suspensionToResponse(suspension))
+}
+
+// liftSuspensions ends a user's active suspensions, or their shadow ban if
+// kind=shadow_ban is given.
+func (cfg *apiConfig) liftSuspensions(w http.ResponseWriter, r *http.Request) {
+	moderator, _ := principalFrom(r.Context())
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid user ID format")
+		return
+	}
+	kind := r.URL.Query().Get("kind")
+	if kind == "" {
+		kind = suspensionKind
+	}
+	if kind != suspensionKind && kind != shadowBanKind {
+		respondWithError(w, 400, "kind must be suspension or shadow_ban")
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "cannot lift suspension")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	lifted, err := qtx.LiftActiveSuspensions(r.Context(), database.LiftActiveSuspensionsParams{UserID: userID, Kind: kind})
+	if err != nil {
+		respondWithError(w, 500, "cannot lift suspension")
+		return
+	}
+	if lifted == 0 {
+		respondWithError(w, 404, "no active "+kind+" found")
+		return
+	}
+	_, err = qtx.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+		ModeratorID: uuid.NullUUID{UUID: moderator.UserID, Valid: true},
+		Action:      "lift_" + kind,
+		UserID:      uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot lift suspension")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, 500, "cannot lift suspension")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// maxThreadDepth bounds how many levels of replies a thread request walks.
+const maxThreadDepth = 32
+
+type threadAncestor struct {
+	chirpResponse
+	Depth int32 `json:"depth"`
+}
+
+type threadChirp struct {
+	chirpResponse
+	Depth   int32          `json:"depth"`
+	Replies []*threadChirp `json:"replies"`
+}
+
+type threadResponse struct {
+	Ancestors []threadAncestor `json:"ancestors"`
+	Chirp     *threadChirp     `json:"chirp"`
+}
+
+// getChirpThread returns the chain of chirps a chirp replies to, root first,
+// and the tree of replies below it, hydrated like any other list of chirps.
+// Deleted and hidden chirps stay in the thread as placeholders so the
+// conversation keeps its shape. Chirps the viewer may not see or has muted
+// are left out, with the replies to them.
+func (cfg *apiConfig) getChirpThread(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid chirp ID format")
+		return
+	}
+	dbChirp, err := cfg.db_query.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+	viewer := cfg.viewer(r)
+	visible, err := cfg.chirpVisibleTo(r.Context(), dbChirp, viewer)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve thread")
+		return
+	}
+	if !visible {
+		respondWithError(w, 404, "Chirp not found")
+		return
+	}
+
+	ancestorRows, err := cfg.db_query.GetChirpAncestors(r.Context(), database.GetChirpAncestorsParams{
+		ID:       chirpID,
+		ViewerID: viewer,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve thread")
+		return
+	}
+	descendantRows, err := cfg.db_query.GetChirpDescendants(r.Context(), database.GetChirpDescendantsParams{
+		ID:       chirpID,
+		ViewerID: viewer,
+		MaxDepth: maxThreadDepth,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve thread")
+		return
+	}
+
+	// the whole thread is hydrated at once: ancestors root first, then the
+	// chirp, then its replies
+	chirps := []database.Chirp{}
+	for _, row := range ancestorRows {
+		chirps = append(chirps, row.Chirp)
+	}
+	chirps = append(chirps, dbChirp)
+	for _, row := range descendantRows {
+		chirps = append(chirps, row.Chirp)
+	}
+	responseChirps := cfg.chirpsToResponse(chirps)
+	err = cfg.hydrateChirps(r.Context(), viewer, responseChirps)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve chirp details")
+		return
+	}
+
+	thread := threadResponse{Ancestors: []threadAncestor{}}
+	for i, row := range ancestorRows {
+		thread.Ancestors = append(thread.Ancestors, threadAncestor{chirpResponse: responseChirps[i], Depth: row.Depth})
+	}
+	responseChirps = responseChirps[len(ancestorRows):]
+	thread.Chirp = &threadChirp{chirpResponse: responseChirps[0], Replies: []*threadChirp{}}
+
+	// rows arrive ordered by depth, so every parent is seen before its replies
+	nodes := map[uuid.UUID]*threadChirp{chirpID: thread.Chirp}
+	for i, row := range descendantRows {
+		parent, ok := nodes[row.Chirp.InReplyTo.UUID]
+		if !ok {
+			continue
+		}
+		node := &threadChirp{
+			chirpResponse: responseChirps[i+1],
+			Depth:         row.Depth,
+			Replies:       []*threadChirp{},
+		}
+		parent.Replies = append(parent.Replies, node)
+		nodes[row.Chirp.ID] = node
+	}
+
+	respondWithJSON(w, 200, thread)
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aklantan/chirpy/internal/auth"
+	"github.com/aklantan/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// totpIssuer names the service in users' authenticator apps
+	totpIssuer           = "Chirpy"
+	challengeTokenExpiry = 5 * time.Minute
+	recoveryCodeCount    = 10
+)
+
+type loginChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+}
+
+// respondWithLoginChallenge answers a login whose password was correct but
+// which still needs a second factor. The challenge token is only accepted by
+// POST /api/login/2fa.
+func (cfg *apiConfig) respondWithLoginChallenge(w http.ResponseWriter, userID uuid.UUID) {
+	challenge, err := auth.MakeChallengeToken(userID, cfg.jwtKeys, challengeTokenExpiry)
+	if err != nil {
+		respondWithError(w, 500, "cannot create challenge token")
+		return
+	}
+	respondWithJSON(w, 200, loginChallengeResponse{TwoFactorRequired: true, ChallengeToken: challenge})
+}
+
+// checkSecondFactor accepts either a current TOTP code that is newer than the
+// last one used, or one of the user's unused recovery codes, which it uses
+// up. Callers must hold the user's user_totp row lock so that two requests
+// cannot both spend the same code, and should go through verifySecondFactor
+// so that wrong codes are throttled.
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, q *database.Queries, totp database.UserTotp, code string) (bool, error) {
+	code = auth.NormalizeRecoveryCode(code)
+	// recovery codes are ten hex digits once normalized; anything else is
+	// taken for a TOTP code
+	if len(code) != 10 {
+		step, ok := auth.ValidateTOTP(totp.Secret, code, time.Now())
+		if !ok || step <= totp.LastUsedStep {
+			return false, nil
+		}
+		err := q.SetTOTPLastUsedStep(ctx, database.SetTOTPLastUsedStepParams{UserID: totp.UserID, LastUsedStep: step})
+		return err == nil, err
+	}
+
+	recoveryCodes, err := q.ListUnusedRecoveryCodes(ctx, totp.UserID)
+	if err != nil {
+		return false, err
+	}
+	for _, recoveryCode := range recoveryCodes {
+		_, err := cfg.passwords.Verify(recoveryCode.CodeHash, code)
+		if errors.Is(err, auth.ErrPasswordMismatch) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		used, err := q.UseRecoveryCode(ctx, recoveryCode.ID)
+		if err != nil {
+			return false, err
+		}
+		return used == 1, nil
+	}
+	return false, nil
+}
+
+// verifySecondFactor checks code for the user whose confirmed 2FA is totp.
+// Wrong codes are throttled per user as totpLoginPolicy requires, whichever
+// endpoint they are sent to, and are also counted against others, such as
+// the challenge token being answered. A wrong code gives errIncorrectCode
+// once tx, which must not hold any other writes yet, has committed the
+// failure. While the user is locked out, verifySecondFactor gives
+// errLoginThrottled and how long to wait without checking the code.
+func (cfg *apiConfig) verifySecondFactor(r *http.Request, tx *sql.Tx, q *database.Queries, totp database.UserTotp, code string, others ...loginSubject) (time.Duration, error) {
+	user := uuid.NullUUID{UUID: totp.UserID, Valid: true}
+	factor := loginSubject{loginAttemptTOTP, totp.UserID.String(), totpLoginPolicy, user}
+	retryAfter, err := lockLoginSubjects(r.Context(), q, []loginSubject{factor})
+	if err != nil {
+		return 0, err
+	}
+	if retryAfter > 0 {
+		return retryAfter, errLoginThrottled
+	}
+	ok, err := cfg.checkSecondFactor(r.Context(), q, totp, code)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		if err := cfg.recordLoginFailure(r, q, append(others, factor)); err != nil {
+			return 0, err
+		}
+		if err := tx.Commit(); err != nil {
+			return 0, err
+		}
+		return 0, errIncorrectCode
+	}
+	return 0, q.ClearLoginFailures(r.Context(), database.ClearLoginFailuresParams{Kind: factor.kind, Subject: factor.subject})
+}
+
+// loginTwoFactor completes a login that loginUser answered with a challenge.
+// Each challenge token can be answered once, and wrong codes are throttled
+// per user as well as per token.
+func (cfg *apiConfig) loginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		w.WriteHeader(400)
+		return
+	}
+	userID, challengeID, err := auth.ValidateChallengeToken(params.ChallengeToken, cfg.jwtKeys)
+	if err != nil {
+		respondWithError(w, 401, "invalid or expired challenge token")
+		return
+	}
+	user := uuid.NullUUID{UUID: userID, Valid: true}
+	challenge := loginSubject{loginAttemptChallenge, challengeID, challengeLoginPolicy, user}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "cannot check code")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	// a challenge that has been answered, or guessed at too often, stays
+	// locked until it expires
+	retryAfter, err := lockLoginSubjects(r.Context(), qtx, []loginSubject{challenge})
+	if err != nil {
+		respondWithError(w, 500, "cannot check code")
+		return
+	}
+	if retryAfter > 0 {
+		respondWithError(w, 401, "invalid or expired challenge token")
+		return
+	}
+
+	totp, err := qtx.GetUserTOTPForUpdate(r.Context(), userID)
+	if err != nil || !totp.ConfirmedAt.Valid {
+		respondWithError(w, 401, "invalid or expired challenge token")
+		return
+	}
+	retryAfter, err = cfg.verifySecondFactor(r, tx, qtx, totp, params.Code, challenge)
+	if err != nil {
+		respondWithSecondFactorError(w, retryAfter, err)
+		return
+	}
+	err = qtx.LockLogin(r.Context(), database.LockLoginParams{
+		LockSeconds: int32(challengeTokenExpiry / time.Second),
+		Kind:        challenge.kind,
+		Subject:     challenge.subject,
+	})
+	if err != nil {
+		respondWithError(w, 500, "cannot check code")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, 500, "cannot check code")
+		return
+	}
+
+	dbUser, err := cfg.db_query.GetUserByID(r.Context(), userID)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve user")
+		return
+	}
+	if !cfg.checkNotSuspended(w, r, userID) {
+		return
+	}
+	cfg.completeLogin(w, r, dbUser)
+}
+
+// enrollTwoFactor starts 2FA enrollment with a fresh secret. Enrollment only
+// takes effect once a code generated from the secret is confirmed.
+func (cfg *apiConfig) enrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type response struct {
+		Secret          string `json:"secret"`
+		ProvisioningURI string `json:"provisioning_uri"`
+	}
+	jwtUser, ok := cfg.authorizeSession(w, r)
+	if !ok {
+		return
+	}
+	dbUser, err := cfg.db_query.GetUserByID(r.Context(), jwtUser)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve user")
+		return
+	}
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		respondWithError(w, 500, "cannot create secret")
+		return
+	}
+	_, err = cfg.db_query.StartTOTPEnrollment(r.Context(), database.StartTOTPEnrollmentParams{UserID: jwtUser, Secret: secret})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 409, "two-factor authentication is already enabled")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "cannot start enrollment")
+		return
+	}
+	respondWithJSON(w, 200, response{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(secret, totpIssuer, dbUser.Email),
+	})
+}
+
+// confirmTwoFactor enables 2FA once the user proves their authenticator has
+// the secret, and hands out the recovery codes. They are only ever shown here.
+func (cfg *apiConfig) confirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Code string `json:"code"`
+	}
+	type response struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}
+	jwtUser, ok := cfg.authorizeSession(w, r)
+	if !ok {
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		w.WriteHeader(400)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "cannot enable two-factor authentication")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	totp, err := qtx.GetUserTOTPForUpdate(r.Context(), jwtUser)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 400, "two-factor enrollment has not been started")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "cannot enable two-factor authentication")
+		return
+	}
+	if totp.ConfirmedAt.Valid {
+		respondWithError(w, 409, "two-factor authentication is already enabled")
+		return
+	}
+	step, ok := auth.ValidateTOTP(totp.Secret, params.Code, time.Now())
+	if !ok {
+		respondWithError(w, 400, "incorrect code")
+		return
+	}
+	err = qtx.ConfirmTOTP(r.Context(), database.ConfirmTOTPParams{UserID: jwtUser, LastUsedStep: step})
+	if err != nil {
+		respondWithError(w, 500, "cannot enable two-factor authentication")
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		respondWithError(w, 500, "cannot create recovery codes")
+		return
+	}
+	err = qtx.DeleteRecoveryCodes(r.Context(), jwtUser)
+	if err != nil {
+		respondWithError(w, 500, "cannot create recovery codes")
+		return
+	}
+	for _, code := range codes {
+		hash, err := cfg.passwords.Hash(auth.NormalizeRecoveryCode(code))
+		if err != nil {
+			respondWithError(w, 500, "cannot create recovery codes")
+			return
+		}
+		err = qtx.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{UserID: jwtUser, CodeHash: hash})
+		if err != nil {
+			respondWithError(w, 500, "cannot create recovery codes")
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, 500, "cannot enable two-factor authentication")
+		return
+	}
+	respondWithJSON(w, 200, response{RecoveryCodes: codes})
+}
+
+// disableTwoFactor turns 2FA off. A stolen access token alone is not enough:
+// the password and a current code or recovery code are both required, and
+// wrong ones are throttled as they are when logging in.
+func (cfg *apiConfig) disableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	jwtUser, ok := cfg.authorizeSession(w, r)
+	if !ok {
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err := decoder.Decode(&params)
+	if err != nil {
+		log.Printf("Error decoding parameters: %s", err)
+		w.WriteHeader(400)
+		return
+	}
+	dbUser, err := cfg.db_query.GetUserByID(r.Context(), jwtUser)
+	if err != nil {
+		respondWithError(w, 500, "cannot retrieve user")
+		return
+	}
+	_, retryAfter, err := cfg.checkLogin(r, dbUser.Email, params.Password)
+	if errors.Is(err, errIncorrectLogin) {
+		respondWithError(w, 401, "incorrect password")
+		return
+	}
+	if err != nil {
+		respondWithLoginError(w, retryAfter, err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		respondWithError(w, 500, "cannot disable two-factor authentication")
+		return
+	}
+	defer tx.Rollback()
+	qtx := cfg.db_query.WithTx(tx)
+
+	totp, err := qtx.GetUserTOTPForUpdate(r.Context(), jwtUser)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 400, "two-factor authentication is not enabled")
+		return
+	}
+	if err != nil {
+		respondWithError(w, 500, "cannot disable two-factor authentication")
+		return
+	}
+	// an enrollment that was never confirmed can be abandoned without a code
+	if totp.ConfirmedAt.Valid {
+		retryAfter, err := cfg.verifySecondFactor(r, tx, qtx, totp, params.Code)
+		if err != nil {
+			respondWithSecondFactorError(w, retryAfter, err)
+			return
+		}
+	}
+	err = qtx.DeleteUserTOTP(r.Context(), jwtUser)
+	if err != nil {
+		respondWithError(w, 500, "cannot disable two-factor authentication")
+		return
+	}
+	err = qtx.DeleteRecoveryCodes(r.Context(), jwtUser)
+	if err != nil {
+		respondWithError(w, 500, "cannot disable two-factor authentication")
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		respondWithError(w, 500, "cannot disable two-factor authentication")
+		return
+	}
+	respondWithJSON(w, 204, nil)
+}